@@ -0,0 +1,932 @@
+// Package check implements the static type checker of palm.
+// It runs between parsing and evaluation and reports type errors before any code runs.
+package check
+
+import (
+	"fmt"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"path/filepath"
+	"sort"
+	"unicode"
+)
+
+// Checker walks a syntax tree, infers the types of expressions and reports type errors.
+// Anything of type interface is only checked at runtime, so it never causes an error.
+type Checker struct {
+	Errors *parse.ErrorContainer
+	// Types holds the inferred type of every checked expression
+	Types map[parse.Node]*Type
+	// Idents holds the checked identifiers of variables and functions, declarations and uses
+	Idents []Ident
+	// Loader loads the imported modules, a loader with the default search path is used if it is nil
+	Loader *module.Loader
+	// modules is shared with the checkers of the imported modules
+	modules *modules
+
+	scope *parse.Scope
+	// top is the scope of the top level of the checked tree
+	top *parse.Scope
+	// function is the type of the function whose body is checked, nil at top level
+	function *Type
+	// returnType is the declared return type of the function whose body is checked
+	returnType *parse.TypeNode
+	pending    []pendingBody
+	errors     []parse.Err
+}
+
+// Ident is an identifier in the checked code. Decl is the identifier declaring the variable, it is the
+// identifier itself for declarations and the zero token for variables defined by the host program.
+type Ident struct {
+	Token parse.Token
+	Decl  parse.Token
+	Type  *Type
+}
+
+// symbol is what the checker stores in scopes for variables, decl is the token declaring it and
+// is zero for variables defined by the host program
+type symbol struct {
+	typ  *Type
+	decl parse.Token
+}
+
+// modules holds the checked modules by file and the chain of the modules being checked
+type modules struct {
+	checked map[string]checkedModule
+	chain   module.Chain
+}
+
+// checkedModule is the type of a checked module, failed is set if the module has errors
+type checkedModule struct {
+	typ    *Type
+	failed bool
+}
+
+// pendingBody is a function body which is checked after the enclosing code, because functions can use
+// names declared after them as long as they are called later.
+type pendingBody struct {
+	node  *parse.FunctionNode
+	typ   *Type
+	scope *parse.Scope
+}
+
+func NewChecker(errors *parse.ErrorContainer) *Checker {
+	return &Checker{Errors: errors, Types: map[parse.Node]*Type{}, scope: parse.NewScope(universe)}
+}
+
+// Check type checks the given tree and returns the found errors
+func Check(tree *parse.SyntaxTree) *parse.ErrorContainer {
+	c := NewChecker(parse.NewErrorContainer())
+	c.Check(tree)
+	return c.Errors
+}
+
+// Define declares a global variable, like the functions registered by a host program
+func (c *Checker) Define(name string, typ *Type) {
+	c.scope.Define(name, &symbol{typ: typ})
+}
+
+// Check type checks the tree in the global scope of the checker, so a checker can check
+// more than one tree like the repl does. Errors are added in the order of their position.
+func (c *Checker) Check(tree *parse.SyntaxTree) {
+	if tree == nil || tree.Root == nil {
+		return
+	}
+
+	// the checked file is part of the chain, so that modules importing it are reported as cycles
+	if tree.Name != "" && c.importModules().chain.Enter(filepath.Clean(tree.Name)) == nil {
+		defer c.modules.chain.Leave()
+	}
+	c.check(tree)
+}
+
+// check checks the tree like Check without adding its file to the chain of the checked modules
+func (c *Checker) check(tree *parse.SyntaxTree) {
+	top := c.top
+	c.top = c.scope
+	defer func() { c.top = top }()
+
+	c.visit(tree.Root)
+	for len(c.pending) > 0 {
+		body := c.pending[0]
+		c.pending = c.pending[1:]
+		c.checkBody(body)
+	}
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		return c.errors[i].Loc.Start.Offset < c.errors[j].Loc.Start.Offset
+	})
+	for _, err := range c.errors {
+		c.Errors.AddError(err)
+	}
+	c.errors = nil
+}
+
+// CheckLocal checks the tree like Check in a scope nested in the global scope, so that the definitions
+// of the tree are discarded. The repl checks its input this way before it runs it.
+func (c *Checker) CheckLocal(tree *parse.SyntaxTree) {
+	c.pushScope()
+	defer c.popScope()
+	c.Check(tree)
+}
+
+// Lookup returns the type of a global variable
+func (c *Checker) Lookup(name string) (*Type, bool) {
+	sym, ok := c.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return sym.typ, true
+}
+
+func (c *Checker) errorAt(token parse.Token, format string, args ...any) {
+	c.errors = append(c.errors, newError(token, parse.Error, format, args...))
+}
+
+// redefinitionError reports the definition of a name already defined in the scope with a note on
+// the previous definition
+func (c *Checker) redefinitionError(token parse.Token, previous *symbol, what string) {
+	err := newError(token, parse.Error, "%s %s already defined", what, token.Val)
+	if previous.decl.Val != "" {
+		err.Notes = []parse.Err{newError(previous.decl, parse.Note, "previous definition of %s", token.Val)}
+	}
+	c.errors = append(c.errors, err)
+}
+
+func newError(token parse.Token, kind parse.ErrorKind, format string, args ...any) parse.Err {
+	return parse.Err{
+		File: token.Loc.Start.Filename,
+		Len:  token.Loc.End.Offset - token.Loc.Start.Offset,
+		Loc:  token.Loc,
+		Msg:  fmt.Sprintf(format, args...),
+		Kind: kind,
+	}
+}
+
+// returnTypeLabel marks the declared return type of the checked function
+func (c *Checker) returnTypeLabel() []parse.Label {
+	if c.returnType == nil || c.returnType.Name.Val == "" {
+		return nil
+	}
+	token := c.returnType.Name
+	return []parse.Label{{
+		Len: token.Loc.End.Offset - token.Loc.Start.Offset,
+		Loc: token.Loc,
+		Msg: "function returns " + c.function.Result.String(),
+	}}
+}
+
+// declare defines the variable declared by the token in the current scope
+func (c *Checker) declare(decl parse.Token, typ *Type) {
+	c.scope.Define(decl.Val, &symbol{typ: typ, decl: decl})
+	c.Idents = append(c.Idents, Ident{Token: decl, Decl: decl, Type: typ})
+}
+
+// use records a use of the variable sym by the identifier
+func (c *Checker) use(token parse.Token, sym *symbol) {
+	c.Idents = append(c.Idents, Ident{Token: token, Decl: sym.decl, Type: sym.typ})
+}
+
+func (c *Checker) lookupLocal(name string) (*symbol, bool) {
+	sym, ok := c.scope.ResolveLocal(name)
+	if !ok {
+		return nil, false
+	}
+	return sym.(*symbol), true
+}
+
+func (c *Checker) lookup(name string) (*symbol, bool) {
+	sym, ok := c.scope.Resolve(name)
+	if !ok {
+		return nil, false
+	}
+	return sym.(*symbol), true
+}
+
+func (c *Checker) pushScope() {
+	c.scope = parse.NewScope(c.scope)
+}
+
+func (c *Checker) popScope() {
+	c.scope = c.scope.Parent()
+}
+
+// visit checks a statement or an expression and returns the type of expressions, nil for statements
+func (c *Checker) visit(node parse.Node) *Type {
+	switch node.Kind() {
+	case parse.NodeProgram:
+		for _, statement := range node.(*parse.ProgramNode).Nodes {
+			c.statement(statement)
+		}
+		return nil
+	case parse.NodeBlockStatement:
+		c.visitBlockStatementNode(node.(*parse.BlockStatementNode))
+		return nil
+	case parse.NodeIfStatement:
+		c.visitIfStatementNode(node.(*parse.IfStatementNode))
+		return nil
+	case parse.NodeElseStatement:
+		c.visit(node.(*parse.ElseStatementNode).Body)
+		return nil
+	case parse.NodeVariableDeclaration:
+		c.visitVariableDeclarationNode(node.(*parse.VariableDeclarationStatementNode))
+		return nil
+	case parse.NodeReturnStatement:
+		c.visitReturnStatementNode(node.(*parse.ReturnStatementNode))
+		return nil
+	case parse.NodeForStatement:
+		c.visitForStatementNode(node.(*parse.ForStatementNode))
+		return nil
+	case parse.NodeBranchStatement:
+		return nil
+	case parse.NodeTypeDeclaration:
+		c.visitTypeDeclarationNode(node.(*parse.TypeDeclarationNode))
+		return nil
+	case parse.NodeImport:
+		c.visitImportNode(node.(*parse.ImportNode))
+		return nil
+	}
+
+	typ := c.visitExpression(node)
+	c.Types[node] = typ
+	return typ
+}
+
+func (c *Checker) visitExpression(node parse.Node) *Type {
+	switch node.Kind() {
+	case parse.NodeNumber:
+		if node.(*parse.NumberNode).NumberKind == parse.NumberFloat {
+			return Float
+		}
+		return Int
+	case parse.NodeBoolean:
+		return Bool
+	case parse.NodeString:
+		return String
+	case parse.NodeArrayLiteral:
+		return c.visitArrayLiteralNode(node.(*parse.ArrayLiteralNode))
+	case parse.NodeIndexExpression:
+		return c.visitIndexExpressionNode(node.(*parse.IndexExpressionNode))
+	case parse.NodeBinaryExpression:
+		return c.visitBinaryExpressionNode(node.(*parse.BinaryExpressionNode))
+	case parse.NodeParenthesisedExpression:
+		return c.expr(node.(*parse.ParenthesisedExpressionNode).Expression)
+	case parse.NodeUnaryExpression:
+		return c.visitUnaryExpressionNode(node.(*parse.UnaryExpressionNode))
+	case parse.NodeAssignmentExpression:
+		return c.visitAssignmentExpressionNode(node.(*parse.AssignmentExpressionNode))
+	case parse.NodeCallExpression:
+		if call := node.(*parse.CallExpressionNode); call.IsCall() {
+			return c.visitCallExpressionNode(call)
+		}
+		return c.visitIdentifierNode(node.(*parse.CallExpressionNode))
+	case parse.NodeFunction:
+		if fn := node.(*parse.FunctionNode); fn.Name.Val != "" {
+			c.errorAt(fn.Name, "function literal can't have a name")
+		}
+		return c.visitFunctionNode(node.(*parse.FunctionNode))
+	case parse.NodeStructLiteral:
+		return c.visitStructLiteralNode(node.(*parse.StructLiteralNode))
+	case parse.NodeSelectorExpression:
+		return c.visitSelectorExpressionNode(node.(*parse.SelectorExpressionNode))
+	}
+	return Interface
+}
+
+// expr checks an expression, types of statements and invalid expressions are interface
+func (c *Checker) expr(node parse.Node) *Type {
+	if typ := c.visit(node); typ != nil {
+		return typ
+	}
+	return Interface
+}
+
+func (c *Checker) visitBlockStatementNode(node *parse.BlockStatementNode) {
+	c.pushScope()
+	defer c.popScope()
+
+	for _, statement := range node.Nodes {
+		c.statement(statement)
+	}
+}
+
+// statement checks a statement of the program or a block, the only places functions can be declared
+func (c *Checker) statement(node parse.Node) {
+	if fn, ok := node.(*parse.FunctionNode); ok && fn.Name.Val != "" {
+		c.Types[node] = c.visitFunctionNode(fn)
+		return
+	}
+	c.visit(node)
+}
+
+func (c *Checker) visitIfStatementNode(node *parse.IfStatementNode) {
+	c.condition(node.IfToken, "if condition", node.Expression)
+	c.visit(node.Body)
+	if node.Else != nil {
+		c.visit(node.Else)
+	}
+}
+
+// condition checks that the condition of an if or for statement is bool
+func (c *Checker) condition(token parse.Token, what string, node parse.Node) {
+	typ := c.expr(node)
+	if typ.Kind != TypeBool && typ.Kind != TypeInterface {
+		c.errorAt(token, "%s must be bool, got %s", what, typ)
+	}
+}
+
+func (c *Checker) visitForStatementNode(node *parse.ForStatementNode) {
+	// variables declared by init statement live in their own scope
+	c.pushScope()
+	defer c.popScope()
+
+	if node.Init != nil {
+		c.visit(node.Init)
+	}
+	if node.Condition != nil {
+		c.condition(node.ForToken, "loop condition", node.Condition)
+	}
+	if node.Post != nil {
+		c.visit(node.Post)
+	}
+	c.visit(node.Body)
+}
+
+func (c *Checker) visitVariableDeclarationNode(node *parse.VariableDeclarationStatementNode) {
+	typ := Interface
+	if node.Expression != nil {
+		typ = c.expr(node.Expression)
+	}
+
+	if previous, ok := c.lookupLocal(node.Identifier.Val); ok {
+		c.redefinitionError(node.Identifier, previous, "variable")
+		return
+	}
+
+	if node.HasTypeToken {
+		declared := c.resolveType(node.Type)
+		if node.Expression != nil && !typ.AssignableTo(declared) {
+			c.errorAt(node.Identifier, "cannot use %s value as %s in declaration of %s", typ, declared, node.Identifier.Val)
+		}
+		typ = declared
+	}
+
+	c.declare(node.Identifier, typ)
+}
+
+func (c *Checker) visitReturnStatementNode(node *parse.ReturnStatementNode) {
+	typ := Interface
+	if node.Expression != nil {
+		typ = c.expr(node.Expression)
+	}
+
+	if c.function == nil || c.function.Result == nil {
+		return
+	}
+	if node.Expression == nil && c.function.Result.Kind != TypeInterface {
+		err := newError(node.ReturnToken, parse.Error, "missing return value, function returns %s", c.function.Result)
+		err.Labels = c.returnTypeLabel()
+		c.errors = append(c.errors, err)
+		return
+	}
+	if !typ.AssignableTo(c.function.Result) {
+		err := newError(node.ReturnToken, parse.Error, "cannot use %s value as %s in return", typ, c.function.Result)
+		err.Labels = c.returnTypeLabel()
+		c.errors = append(c.errors, err)
+	}
+}
+
+func (c *Checker) visitTypeDeclarationNode(node *parse.TypeDeclarationNode) {
+	if _, ok := builtinTypes[node.Name.Val]; ok {
+		c.errorAt(node.Name, "cannot redeclare builtin type %s", node.Name.Val)
+		return
+	}
+	if _, ok := c.scope.ResolveLocalType(node.Name.Val); ok {
+		c.errorAt(node.Name, "type %s already defined", node.Name.Val)
+		return
+	}
+
+	if node.Type.IsStruct() {
+		// struct is defined before its fields are resolved, so that it can refer to itself
+		typ := &Type{Kind: TypeStruct, Name: node.Name.Val}
+		c.scope.DefineType(node.Name.Val, typ)
+		c.resolveFields(typ, node.Type)
+		return
+	}
+
+	c.scope.DefineType(node.Name.Val, c.resolveType(node.Type))
+}
+
+// resolveType converts a type annotation to a type, unknown types are reported and become interface
+func (c *Checker) resolveType(node *parse.TypeNode) *Type {
+	if node.IsArray() {
+		return ArrayOf(c.resolveType(node.Elem))
+	}
+
+	if node.IsStruct() {
+		typ := &Type{Kind: TypeStruct, Name: node.String()}
+		c.resolveFields(typ, node)
+		return typ
+	}
+
+	if typ, ok := builtinTypes[node.Name.Val]; ok {
+		return typ
+	}
+
+	typ, ok := c.scope.ResolveType(node.Name.Val)
+	if !ok {
+		c.errorAt(node.Name, "unknown type %s", node.Name.Val)
+		return Interface
+	}
+	return typ.(*Type)
+}
+
+func (c *Checker) resolveFields(typ *Type, node *parse.TypeNode) {
+	for _, field := range node.Fields {
+		if typ.FieldIndex(field.Name.Val) >= 0 {
+			c.errorAt(field.Name, "duplicate field %s", field.Name.Val)
+			continue
+		}
+		typ.Fields = append(typ.Fields, Field{Name: field.Name.Val, Type: c.resolveType(field.Type)})
+	}
+}
+
+// visitArrayLiteralNode infers the element type like arrays created at runtime.
+// Mixed int and float elements make a float array, other mixtures make an interface array.
+func (c *Checker) visitArrayLiteralNode(node *parse.ArrayLiteralNode) *Type {
+	if len(node.Elements) == 0 {
+		return ArrayOf(Interface)
+	}
+
+	elem := c.expr(node.Elements[0])
+	for _, element := range node.Elements[1:] {
+		elemType := c.expr(element)
+		switch {
+		case elem.Equal(elemType):
+		case elem.isNumeric() && elemType.isNumeric():
+			elem = Float
+		default:
+			elem = Interface
+		}
+	}
+	return ArrayOf(elem)
+}
+
+func (c *Checker) visitIndexExpressionNode(node *parse.IndexExpressionNode) *Type {
+	left := c.expr(node.Left)
+	index := c.expr(node.Index)
+
+	if index.Kind != TypeInt && index.Kind != TypeInterface {
+		c.errorAt(node.LBracket, "array index must be int, got %s", index)
+	}
+
+	switch left.Kind {
+	case TypeArray:
+		return left.Elem
+	case TypeInterface:
+		return Interface
+	}
+	c.errorAt(node.LBracket, "cannot index value of type %s", left)
+	return Interface
+}
+
+func (c *Checker) visitSelectorExpressionNode(node *parse.SelectorExpressionNode) *Type {
+	left := c.expr(node.Left)
+
+	switch left.Kind {
+	case TypeStruct:
+		index := left.FieldIndex(node.Field.Val)
+		if index < 0 {
+			c.errorAt(node.Field, "type %s has no field %s", left, node.Field.Val)
+			return Interface
+		}
+		return left.Fields[index].Type
+	case TypeModule:
+		// the fields are the exported members, all members of the modules of the standard library
+		if index := left.FieldIndex(node.Field.Val); index >= 0 {
+			return left.Fields[index].Type
+		}
+		if !module.IsExported(node.Field.Val) && !isBuiltinModule(left) {
+			c.errorAt(node.Field, "cannot refer to unexported name %s.%s", left.Name, node.Field.Val)
+			return Interface
+		}
+		c.errorAt(node.Field, "module %s has no exported name %s", left.Name, node.Field.Val)
+		return Interface
+	case TypeInterface:
+		return Interface
+	}
+	c.errorAt(node.Field, "cannot access field %s of %s value", node.Field.Val, left)
+	return Interface
+}
+
+// visitImportNode checks the imported module and declares its name with the type of the module
+func (c *Checker) visitImportNode(node *parse.ImportNode) {
+	// the path is the declaration of the module name
+	decl := node.Path.Token
+	decl.Val = node.Name()
+	if !isIdentifier(decl.Val) {
+		c.errorAt(node.Path.Token, "import path %s does not end in a valid module name", node.Path.Token.Val)
+		return
+	}
+
+	typ := c.importModule(node)
+	if previous, ok := c.lookupLocal(decl.Val); ok {
+		// importing a module again like the repl may do is fine
+		if previous.typ != typ || typ.Kind != TypeModule {
+			c.redefinitionError(decl, previous, "module")
+		}
+		return
+	}
+	c.declare(decl, typ)
+}
+
+// importModule loads and checks the module of the import, every module is checked once. The errors of
+// the module are added to the errors of the checker with the file name of the module and the import
+// of a module with errors is an error too.
+func (c *Checker) importModule(node *parse.ImportNode) *Type {
+	modules := c.importModules()
+	if c.Loader == nil {
+		c.Loader = module.NewLoader(module.DefaultSearchPath())
+	}
+	file, err := c.Loader.Load(node.Path.Val, node.ImportToken.Loc.Start.Filename)
+	if err != nil {
+		c.errorAt(node.Path.Token, "%s", err)
+		return Interface
+	}
+
+	checked, ok := modules.checked[file.Path]
+	if !ok {
+		if err := modules.chain.Enter(file.Path); err != nil {
+			c.errorAt(node.Path.Token, "%s", err)
+			return Interface
+		}
+		checked = c.checkModule(node.Name(), file, modules)
+		modules.chain.Leave()
+		modules.checked[file.Path] = checked
+	}
+	if checked.failed {
+		c.errorAt(node.Path.Token, "module %s has errors", node.Name())
+	}
+	return checked.typ
+}
+
+// checkModule checks the file of a module in a new checker, the type of the module has its exported
+// global variables and functions as fields
+func (c *Checker) checkModule(name string, file *module.File, modules *modules) checkedModule {
+	checker := NewChecker(parse.NewErrorContainer())
+	checker.Loader, checker.modules = c.Loader, modules
+	if len(file.Errors) > 0 {
+		for _, err := range file.Errors {
+			checker.Errors.AddError(err)
+		}
+	} else {
+		checker.check(file.Tree)
+	}
+	for _, err := range checker.Errors.GetErrors() {
+		c.Errors.AddError(err)
+	}
+
+	typ := &Type{Kind: TypeModule, Name: name}
+	for _, name := range checker.scope.Names() {
+		if sym, _ := checker.lookupLocal(name); module.IsExported(name) {
+			typ.Fields = append(typ.Fields, Field{Name: name, Type: sym.typ})
+		}
+	}
+	return checkedModule{typ: typ, failed: checker.Errors.HasErrors()}
+}
+
+func (c *Checker) importModules() *modules {
+	if c.modules == nil {
+		c.modules = &modules{checked: map[string]checkedModule{}}
+	}
+	return c.modules
+}
+
+// isIdentifier reports whether the name can be used as an identifier, identifiers consist of letters
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Checker) visitStructLiteralNode(node *parse.StructLiteralNode) *Type {
+	resolved, ok := c.scope.ResolveType(node.TypeName.Val)
+	if !ok {
+		c.errorAt(node.TypeName, "unknown type %s", node.TypeName.Val)
+		for _, field := range node.Fields {
+			c.expr(field.Value)
+		}
+		return Interface
+	}
+
+	typ := resolved.(*Type)
+	if typ.Kind != TypeStruct {
+		c.errorAt(node.TypeName, "%s is not a struct type", node.TypeName.Val)
+		return Interface
+	}
+
+	for _, field := range node.Fields {
+		val := c.expr(field.Value)
+		index := typ.FieldIndex(field.Name.Val)
+		if index < 0 {
+			c.errorAt(field.Name, "unknown field %s in struct literal of type %s", field.Name.Val, typ)
+			continue
+		}
+		if !val.AssignableTo(typ.Fields[index].Type) {
+			c.errorAt(field.Name, "cannot use %s value as %s in field %s", val, typ.Fields[index].Type, field.Name.Val)
+		}
+	}
+	return typ
+}
+
+func (c *Checker) visitBinaryExpressionNode(node *parse.BinaryExpressionNode) *Type {
+	left := c.expr(node.Left)
+	right := c.expr(node.Right)
+	return c.binaryOperator(node.Op, left, right)
+}
+
+// isComparison reports whether op always results in a bool
+func isComparison(op parse.Token) bool {
+	switch op.Kind {
+	case parse.EQ, parse.NEQ, parse.LT, parse.LTE, parse.GT, parse.GTE:
+		return true
+	}
+	return false
+}
+
+// binaryOperator returns the result type of op following the rules of the evaluator:
+// strings only operate on strings, ints are promoted to floats and bools support && and ||.
+func (c *Checker) binaryOperator(op parse.Token, left, right *Type) *Type {
+	mismatched := func() *Type {
+		c.errorAt(op, "mismatched types %s and %s for operator %s", left, right, op.Val)
+		return Interface
+	}
+	notDefined := func(typ *Type) *Type {
+		c.errorAt(op, "operator %s is not defined on %s", op.Val, typ)
+		return Interface
+	}
+
+	if left.Kind == TypeString || right.Kind == TypeString {
+		if left.Kind != right.Kind && left.Kind != TypeInterface && right.Kind != TypeInterface {
+			return mismatched()
+		}
+		switch {
+		case op.Kind == parse.PLUS:
+			return String
+		case isComparison(op):
+			return Bool
+		}
+		return notDefined(String)
+	}
+
+	if left.Kind == TypeInterface || right.Kind == TypeInterface {
+		if isComparison(op) {
+			return Bool
+		}
+		return Interface
+	}
+
+	if left.Kind == TypeFloat || right.Kind == TypeFloat {
+		if !left.isNumeric() || !right.isNumeric() {
+			return mismatched()
+		}
+		switch op.Kind {
+		case parse.PLUS, parse.MINUS, parse.MUL, parse.QUO, parse.REM:
+			return Float
+		}
+		if isComparison(op) {
+			return Bool
+		}
+		return notDefined(Float)
+	}
+
+	if op.Kind == parse.EQ || op.Kind == parse.NEQ {
+		if !left.Equal(right) {
+			return mismatched()
+		}
+		return Bool
+	}
+
+	if left.Kind == TypeBool && right.Kind == TypeBool {
+		if op.Kind == parse.AND || op.Kind == parse.OR {
+			return Bool
+		}
+		return notDefined(Bool)
+	}
+
+	if left.Kind != TypeInt || right.Kind != TypeInt {
+		if left.Kind == TypeInt || right.Kind == TypeInt || left.Equal(right) {
+			return mismatched()
+		}
+		return notDefined(left)
+	}
+
+	switch op.Kind {
+	case parse.PLUS, parse.MINUS, parse.MUL, parse.QUO, parse.REM, parse.AND, parse.OR,
+		parse.BITAND, parse.BITOR, parse.XOR, parse.LSHIFT, parse.RSHIFT:
+		return Int
+	}
+	if isComparison(op) {
+		return Bool
+	}
+	return notDefined(Int)
+}
+
+func (c *Checker) visitUnaryExpressionNode(node *parse.UnaryExpressionNode) *Type {
+	right := c.expr(node.Right)
+
+	switch right.Kind {
+	case TypeInterface:
+		if node.Op.Kind == parse.NOT {
+			return Bool
+		}
+		return Interface
+	case TypeInt, TypeFloat:
+		if node.Op.Kind == parse.PLUS || node.Op.Kind == parse.MINUS {
+			return right
+		}
+	case TypeBool:
+		if node.Op.Kind == parse.NOT {
+			return Bool
+		}
+	}
+
+	c.errorAt(node.Op, "operator %s is not defined on %s", node.Op.Val, right)
+	return Interface
+}
+
+func (c *Checker) visitAssignmentExpressionNode(node *parse.AssignmentExpressionNode) *Type {
+	var target *Type
+	var what string
+	switch t := node.Target.(type) {
+	case *parse.IndexExpressionNode:
+		target, what = c.expr(t), "array assignment"
+	case *parse.SelectorExpressionNode:
+		target, what = c.expr(t), "assignment to field "+t.Field.Val
+		if left := c.Types[t.Left]; left != nil && left.Kind == TypeModule {
+			c.errorAt(t.Field, "cannot assign to %s.%s, module members are read only", left.Name, t.Field.Val)
+			c.expr(node.Right)
+			return target
+		}
+	default:
+		resolved, ok := c.lookup(node.Identifier.Val)
+		if !ok {
+			val := c.expr(node.Right)
+			if node.Op.Kind != parse.ASSIGN || c.scope != c.top {
+				c.errorAt(node.Identifier, "variable %s not defined", node.Identifier.Val)
+				return Interface
+			}
+			// assigning an undefined variable at top level defines it like the evaluator does
+			c.declare(node.Identifier, val)
+			return val
+		}
+		c.use(node.Identifier, resolved)
+		if builtin, ok := universe.ResolveLocal(node.Identifier.Val); ok && builtin == resolved {
+			c.errorAt(node.Identifier, "cannot assign to builtin %s", node.Identifier.Val)
+			c.expr(node.Right)
+			return resolved.typ
+		}
+		target, what = resolved.typ, "assignment to "+node.Identifier.Val
+	}
+
+	val := c.expr(node.Right)
+	if node.Op.Kind != parse.ASSIGN {
+		val = c.binaryOperator(parse.CompoundAssignOperator(node.Op), target, val)
+	}
+	if !val.AssignableTo(target) {
+		c.errorAt(node.Op, "cannot use %s value as %s in %s", val, target, what)
+	}
+	return target
+}
+
+func (c *Checker) visitIdentifierNode(node *parse.CallExpressionNode) *Type {
+	sym, ok := c.lookup(node.Identifier.Val)
+	if !ok {
+		c.errorAt(node.Identifier, "undefined variable %s", node.Identifier.Val)
+		return Interface
+	}
+	c.use(node.Identifier, sym)
+	return sym.typ
+}
+
+func (c *Checker) visitCallExpressionNode(node *parse.CallExpressionNode) *Type {
+	callee := c.expr(node.Callee)
+	args := make([]*Type, len(node.Args))
+	for i, arg := range node.Args {
+		args[i] = c.expr(arg)
+	}
+
+	switch {
+	case callee.Kind == TypeInterface || callee.Kind == TypeFunction && !callee.Signature:
+		return Interface
+	case callee.Kind != TypeFunction:
+		c.errorAt(node.LParen, "cannot call non-function %s of type %s", node.Callee, callee)
+		return Interface
+	}
+
+	switch {
+	case callee.Variadic && len(args) < len(callee.Params)-1:
+		c.errorAt(node.LParen, "%s expects at least %d arguments, got %d", node.Callee, len(callee.Params)-1, len(args))
+	case !callee.Variadic && len(args) != len(callee.Params):
+		c.errorAt(node.LParen, "%s expects %d arguments, got %d", node.Callee, len(callee.Params), len(args))
+	default:
+		for i, arg := range args {
+			param := callee.Params[len(callee.Params)-1]
+			if i < len(callee.Params) {
+				param = callee.Params[i]
+			}
+			if !arg.AssignableTo(param) {
+				c.errorAt(node.LParen, "cannot use %s value as %s in argument %d of %s", arg, param, i+1, node.Callee)
+			}
+		}
+	}
+
+	if callee.Result == nil {
+		return Interface
+	}
+	return callee.Result
+}
+
+// visitFunctionNode declares named functions, the body is checked later by checkBody
+func (c *Checker) visitFunctionNode(node *parse.FunctionNode) *Type {
+	typ := &Type{Kind: TypeFunction, Signature: true, Params: make([]*Type, len(node.Params))}
+	for i, param := range node.Params {
+		typ.Params[i] = c.resolveType(param.Type)
+	}
+	if node.ReturnType != nil {
+		typ.Result = c.resolveType(node.ReturnType)
+	}
+
+	// named functions are declarations, anonymous ones are just values
+	if node.Name.Val != "" {
+		if previous, ok := c.lookupLocal(node.Name.Val); ok {
+			c.redefinitionError(node.Name, previous, "function")
+		} else {
+			c.declare(node.Name, typ)
+		}
+	}
+
+	c.pending = append(c.pending, pendingBody{node: node, typ: typ, scope: c.scope})
+	return typ
+}
+
+func (c *Checker) checkBody(body pendingBody) {
+	scope, function, returnType := c.scope, c.function, c.returnType
+	c.scope = parse.NewScope(body.scope)
+	c.function, c.returnType = body.typ, body.node.ReturnType
+	for i, param := range body.node.Params {
+		c.declare(param.Name, body.typ.Params[i])
+	}
+
+	c.visit(body.node.Body)
+	// functions returning interface return nil at the end of their body
+	if result := body.typ.Result; result != nil && result.Kind != TypeInterface && !terminates(body.node.Body) {
+		err := newError(body.node.Body.Right, parse.Error, "missing return, function returns %s", result)
+		err.Labels = c.returnTypeLabel()
+		c.errors = append(c.errors, err)
+	}
+	c.scope, c.function, c.returnType = scope, function, returnType
+}
+
+// terminates reports whether the statement never completes normally, like a return statement or an
+// if statement whose branches both terminate
+func terminates(node parse.Node) bool {
+	switch node := node.(type) {
+	case *parse.ReturnStatementNode:
+		return true
+	case *parse.BlockStatementNode:
+		return len(node.Nodes) > 0 && terminates(node.Nodes[len(node.Nodes)-1])
+	case *parse.IfStatementNode:
+		return node.Else != nil && terminates(node.Body) && terminates(node.Else)
+	case *parse.ElseStatementNode:
+		return terminates(node.Body)
+	case *parse.ForStatementNode:
+		return node.Condition == nil && !breaks(node.Body)
+	}
+	return false
+}
+
+// breaks reports whether the statement contains a break statement of the enclosing loop
+func breaks(node parse.Node) bool {
+	switch node := node.(type) {
+	case *parse.BranchStatementNode:
+		return node.Token.Kind == parse.BREAK
+	case *parse.BlockStatementNode:
+		for _, statement := range node.Nodes {
+			if breaks(statement) {
+				return true
+			}
+		}
+	case *parse.IfStatementNode:
+		return breaks(node.Body) || node.Else != nil && breaks(node.Else)
+	case *parse.ElseStatementNode:
+		return breaks(node.Body)
+	}
+	return false
+}
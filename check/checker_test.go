@@ -0,0 +1,72 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"myProgrammingLanguage/parse"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// accepted programs
+		{"x := 1\ny := 2.5\nz := x + y\nfloat w = z", ""},
+		{"s := \"a\" + \"b\"\nn := len(s)", ""},
+		{"fn fib(int n) int {\nif n < 2 { return n }\nreturn fib(n - 1) + fib(n - 2)\n}\nfib(10)", ""},
+		{"type P = struct { int x }\np := P{x: 1}\np.x = 2\np.x + 1", ""},
+		{"x := 1\nfn f() { x = 2 }\nf()", ""},
+		{"found = 5\nfound += 1", ""},
+		{"math.sqrt(2.0)", ""},
+
+		// type errors
+		{"x := 1 + \"a\"", "1:8: mismatched types int and string for operator +"},
+		{"x := 1\nx += \"a\"", "2:3: mismatched types int and string for operator +"},
+		{"x := !1", "1:6: operator ! is not defined on int"},
+		{"int x = \"s\"", "1:5: cannot use string value as int in declaration of x"},
+		{"x := [1, 2]\nx[0] = \"s\"", "2:6: cannot use string value as int in array assignment"},
+		{"if 1 { }", "1:1: if condition must be bool, got int"},
+		{"for i := 0; i; i += 1 { }", "1:1: loop condition must be bool, got int"},
+		{"type P = struct { int x }\np := P{x: \"s\"}", "2:8: cannot use string value as int in field x"},
+		{"type P = struct { int x }\np := P{x: 1}\np.y", "3:3: type P has no field y"},
+
+		// names
+		{"y := z", "1:6: undefined variable z"},
+		{"x := 1\nx := 2", "2:1: variable x already defined"},
+		{"len = 1", "1:1: cannot assign to builtin len"},
+		{"if true { found = 5 }", "1:11: variable found not defined"},
+
+		// calls
+		{"fn f(int a) int { return a }\nf(\"s\")", "2:2: cannot use string value as int in argument 1 of f"},
+		{"fn f(int a) int { return a }\nf(1, 2)", "2:2: f expects 1 arguments, got 2"},
+
+		// returns
+		{"fn f() int { return \"s\" }", "1:14: cannot use string value as int in return"},
+		{"fn f() int { return }", "1:14: missing return value, function returns int"},
+		{"fn f() int { }", "1:14: missing return, function returns int"},
+		{"fn f(int n) int { if n > 0 { return 1 } }", "1:41: missing return, function returns int"},
+		{"fn f(int n) int { if n > 0 { return 1 } else if n < 0 { return 2 } }", "1:68: missing return, function returns int"},
+		{"fn f(int n) int { if n > 0 { return 1 } else if n < 0 { return 2 } else { return 3 } }", ""},
+		{"fn f() int { for { if true { break } } }", "1:40: missing return, function returns int"},
+		{"fn f() int { for { for { break } } }", ""},
+		{"fn f() int { return 1\nx := 2 }", "2:8: missing return, function returns int"},
+		{"fn f() interface { }\nfn g() { }", ""},
+	}
+	for _, test := range tests {
+		parser := parse.NewParser("test.pd", test.src)
+		tree, err := parser.Parse()
+		if err != nil || parser.Errors.HasErrors() {
+			t.Fatalf("%q: parse errors %v %v", test.src, err, parser.Errors.GetErrors())
+		}
+		var got []string
+		for _, err := range Check(tree).GetErrors() {
+			got = append(got, fmt.Sprintf("%d:%d: %s", err.Loc.Start.Line+1, err.Loc.Start.Col+1, err.Msg))
+		}
+		if strings.Join(got, "\n") != test.want {
+			t.Errorf("%q: got errors\n%s\nwant\n%s", test.src, strings.Join(got, "\n"), test.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package check
+
+import "strings"
+
+type TypeKind int
+
+const (
+	TypeInterface TypeKind = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeString
+	TypeArray
+	TypeFunction
+	TypeStruct
+	// TypeModule is the type of imported modules, Fields holds their exported members
+	TypeModule
+)
+
+// Type is the static type of palm expressions.
+// Unlike runtime types, function types may know their signature: Params and Result are only set
+// if Signature is true and Result is nil for functions without a return type. The last parameter of
+// variadic functions like print takes any number of arguments.
+type Type struct {
+	Kind      TypeKind
+	Elem      *Type
+	Name      string
+	Fields    []Field
+	Signature bool
+	Params    []*Type
+	Variadic  bool
+	Result    *Type
+}
+
+type Field struct {
+	Name string
+	Type *Type
+}
+
+var (
+	Interface = &Type{Kind: TypeInterface}
+	Int       = &Type{Kind: TypeInt}
+	Float     = &Type{Kind: TypeFloat}
+	Bool      = &Type{Kind: TypeBool}
+	String    = &Type{Kind: TypeString}
+	Function  = &Type{Kind: TypeFunction}
+)
+
+var builtinTypes = map[string]*Type{
+	"interface": Interface,
+	"int":       Int,
+	"float":     Float,
+	"bool":      Bool,
+	"string":    String,
+	"fn":        Function,
+}
+
+func ArrayOf(elem *Type) *Type {
+	return &Type{Kind: TypeArray, Elem: elem}
+}
+
+// FieldIndex returns the index of the field with the given name or -1 if there is no such field
+func (t *Type) FieldIndex(name string) int {
+	for i, field := range t.Fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *Type) String() string {
+	switch t.Kind {
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	case TypeString:
+		return "string"
+	case TypeArray:
+		return t.Elem.String() + "[]"
+	case TypeFunction:
+		if !t.Signature {
+			return "fn"
+		}
+		params := make([]string, len(t.Params))
+		for i, param := range t.Params {
+			params[i] = param.String()
+		}
+		if t.Variadic {
+			params[len(params)-1] += "..."
+		}
+		if t.Result == nil {
+			return "fn(" + strings.Join(params, ", ") + ")"
+		}
+		return "fn(" + strings.Join(params, ", ") + ") " + t.Result.String()
+	case TypeStruct:
+		return t.Name
+	case TypeModule:
+		return "module " + t.Name
+	}
+	return "interface"
+}
+
+// Equal reports whether the types are identical, function types are equal regardless of their signatures
+// like at runtime.
+func (t *Type) Equal(other *Type) bool {
+	if t.Kind != other.Kind {
+		return false
+	}
+	if t.Kind == TypeArray {
+		return t.Elem.Equal(other.Elem)
+	}
+	if t.Kind == TypeStruct || t.Kind == TypeModule {
+		return t == other
+	}
+	return true
+}
+
+// AssignableTo reports whether a value of type t can be stored in a variable of type dst.
+// Interface values may hold anything, so they are only checked at runtime.
+func (t *Type) AssignableTo(dst *Type) bool {
+	if dst.Kind == TypeInterface || t.Kind == TypeInterface {
+		return true
+	}
+	if dst.Kind == TypeFloat && t.Kind == TypeInt {
+		return true
+	}
+	if dst.Kind == TypeArray && t.Kind == TypeArray {
+		// arrays are converted element by element
+		return t.Elem.AssignableTo(dst.Elem)
+	}
+	return t.Equal(dst)
+}
+
+func (t *Type) isNumeric() bool {
+	return t.Kind == TypeInt || t.Kind == TypeFloat
+}
@@ -0,0 +1,79 @@
+package check
+
+import (
+	"myProgrammingLanguage/parse"
+	"sort"
+)
+
+// universe is the outer scope of the global scopes of checkers, it declares the builtins and the modules
+// of the standard library implemented by the eval package
+var universe = newUniverse()
+
+func newUniverse() *parse.Scope {
+	scope := parse.NewScope(nil)
+	define := func(name string, typ *Type) {
+		scope.Define(name, &symbol{typ: typ})
+	}
+
+	define("print", &Type{Kind: TypeFunction, Signature: true, Params: []*Type{Interface}, Variadic: true})
+	define("println", &Type{Kind: TypeFunction, Signature: true, Params: []*Type{Interface}, Variadic: true})
+	define("len", signature(Int, Interface))
+	define("assert", signature(nil, Bool, String))
+	define("assertEq", signature(nil, Interface, Interface))
+
+	define("math", builtinModule("math", map[string]*Type{
+		"abs":  signature(Float, Float),
+		"min":  signature(Float, Float, Float),
+		"max":  signature(Float, Float, Float),
+		"pow":  signature(Float, Float, Float),
+		"sqrt": signature(Float, Float),
+	}))
+	define("strings", builtinModule("strings", map[string]*Type{
+		"split":    signature(ArrayOf(String), String, String),
+		"join":     signature(String, ArrayOf(String), String),
+		"contains": signature(Bool, String, String),
+		"upper":    signature(String, String),
+		"lower":    signature(String, String),
+	}))
+	define("io", builtinModule("io", map[string]*Type{
+		"readLine":  signature(String),
+		"readFile":  signature(String, String),
+		"writeFile": signature(nil, String, String),
+	}))
+	define("time", builtinModule("time", map[string]*Type{
+		"now":   signature(Int),
+		"sleep": signature(nil, Int),
+	}))
+	return scope
+}
+
+// signature returns the type of a function with the given parameters, result is nil for functions
+// without a result
+func signature(result *Type, params ...*Type) *Type {
+	return &Type{Kind: TypeFunction, Signature: true, Params: params, Result: result}
+}
+
+// builtinModule returns the type of a module of the standard library, its members are sorted by name
+// like the members of imported modules
+func builtinModule(name string, members map[string]*Type) *Type {
+	typ := &Type{Kind: TypeModule, Name: name}
+	for _, name := range sortedNames(members) {
+		typ.Fields = append(typ.Fields, Field{Name: name, Type: members[name]})
+	}
+	return typ
+}
+
+// isBuiltinModule reports whether the type is the type of a module of the standard library
+func isBuiltinModule(typ *Type) bool {
+	sym, ok := universe.ResolveLocal(typ.Name)
+	return ok && sym.(*symbol).typ == typ
+}
+
+func sortedNames(members map[string]*Type) []string {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"myProgrammingLanguage/check"
+	"myProgrammingLanguage/eval"
+	"myProgrammingLanguage/lsp"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// command runs a subcommand with its arguments and returns the exit code
+type command func(args []string) int
+
+var commands = map[string]command{
+	"run":      runCommand,
+	"repl":     replCommand,
+	"check":    checkCommand,
+	"tokens":   tokensCommand,
+	"ast":      astCommand,
+	"difftest": difftestCommand,
+	"fmt":      fmtCommand,
+	"lsp":      lspCommand,
+	"doc":      docCommand,
+	"test":     testCommand,
+	"debug":    debugCommand,
+}
+
+// sourceFlags are the flags shared by the commands which read a palm file
+type sourceFlags struct {
+	stdin  *bool
+	format *string
+	color  *string
+}
+
+func newFlagSet(name string, withFormat bool) (*flag.FlagSet, sourceFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: palm %s [flags] [file]\n", name)
+		fs.PrintDefaults()
+	}
+
+	flags := sourceFlags{
+		stdin: fs.Bool("stdin", false, "read the source from standard input instead of a file"),
+		color: fs.String("color", "auto", "color diagnostics, auto, always or never"),
+	}
+	if withFormat {
+		flags.format = fs.String("format", "text", "output format, text or json")
+	}
+	return fs, flags
+}
+
+// readSource reads the file given as the only argument or the standard input if -stdin is set
+func readSource(fs *flag.FlagSet, flags sourceFlags) (string, string, error) {
+	if *flags.stdin {
+		src, err := io.ReadAll(os.Stdin)
+		return "<stdin>", string(src), err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", "", fmt.Errorf("expected exactly one file")
+	}
+
+	src, err := os.ReadFile(fs.Arg(0))
+	return fs.Arg(0), string(src), err
+}
+
+func checkFormat(flags sourceFlags) error {
+	if *flags.format != "text" && *flags.format != "json" {
+		return fmt.Errorf("unknown format %q, expected text or json", *flags.format)
+	}
+	return nil
+}
+
+// newRenderer returns a renderer for diagnostics written to f. With -color auto diagnostics are colored
+// if f is a terminal and the NO_COLOR environment variable is not set.
+func newRenderer(flags sourceFlags, f *os.File) (*parse.Renderer, error) {
+	switch *flags.color {
+	case "always":
+		return parse.NewRenderer(true), nil
+	case "never":
+		return parse.NewRenderer(false), nil
+	case "auto":
+		info, err := f.Stat()
+		terminal := err == nil && info.Mode()&os.ModeCharDevice != 0
+		return parse.NewRenderer(terminal && os.Getenv("NO_COLOR") == ""), nil
+	}
+	return nil, fmt.Errorf("unknown color mode %q, expected auto, always or never", *flags.color)
+}
+
+func writeJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// parseSource parses the source and renders the syntax errors to stderr, it returns false if there are errors
+func parseSource(name, src string, renderer *parse.Renderer) (*parse.SyntaxTree, bool) {
+	renderer.AddSource(name, src)
+	parser := parse.NewParser(name, src)
+	tree, err := parser.Parse()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+
+	if parser.Errors.HasErrors() {
+		renderer.RenderAll(os.Stderr, parser.Errors.GetErrors())
+		return tree, false
+	}
+	return tree, true
+}
+
+func runCommand(args []string) int {
+	fs, flags := newFlagSet("run", false)
+	engineName := fs.String("engine", "tree", "engine running the program, tree for the tree walker or vm for the bytecode vm")
+	var limits eval.Limits
+	fs.Int64Var(&limits.MaxSteps, "max-steps", 0, "stop after the given number of evaluation steps, 0 for no limit")
+	fs.IntVar(&limits.MaxDepth, "max-depth", 0, "maximum depth of function calls up to 10000, 0 for 10000")
+	fs.IntVar(&limits.MaxCollectionSize, "max-size", 0, "maximum number of array elements and string bytes, 0 for no limit")
+	timeout := fs.Duration("timeout", 0, "stop the program after the given time like 2s, 0 for no limit")
+	path := searchPathFlag(fs)
+	fs.Parse(args)
+
+	name, src, err := readSource(fs, flags)
+	if err == nil {
+		_, err = eval.ParseEngine(*engineName)
+	}
+	var renderer *parse.Renderer
+	if err == nil {
+		renderer, err = newRenderer(flags, os.Stderr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	tree, ok := parseSource(name, src, renderer)
+	if !ok {
+		return 1
+	}
+	loader := module.NewLoader(searchPath(*path))
+	checker := check.NewChecker(parse.NewErrorContainer())
+	checker.Loader = loader
+	checker.Check(tree)
+	addModuleSources(renderer, loader)
+	if checker.Errors.HasErrors() {
+		renderer.RenderAll(os.Stderr, checker.Errors.GetErrors())
+		return 1
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	engine, _ := eval.ParseEngine(*engineName)
+	result, err := eval.NewImporter(loader, engine, limits).Run(ctx, name, tree, eval.NewGlobalScope())
+	if err != nil {
+		printRuntimeError(err, renderer)
+		return 1
+	}
+
+	if result != nil {
+		fmt.Println(result)
+	}
+	return 0
+}
+
+// searchPathFlag defines the -path flag of the commands running or checking programs with imports
+func searchPathFlag(fs *flag.FlagSet) *string {
+	return fs.String("path", "", "directories searched for imported modules separated like PATH, $PALM_PATH by default")
+}
+
+// searchPath returns the directories of the -path flag or the default search path if it isn't set
+func searchPath(path string) []string {
+	if path == "" {
+		return module.DefaultSearchPath()
+	}
+	return filepath.SplitList(path)
+}
+
+// addModuleSources adds the sources of the loaded modules to the renderer, so that their errors show the source
+func addModuleSources(renderer *parse.Renderer, loader *module.Loader) {
+	for _, file := range loader.Files() {
+		renderer.AddSource(file.Path, file.Src)
+	}
+}
+
+// printRuntimeError renders the error and prints the stack trace of runtime errors to stderr
+func printRuntimeError(err error, renderer *parse.Renderer) {
+	var runtimeErr *eval.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	renderer.Render(os.Stderr, runtimeErr.Diagnostic())
+	if len(runtimeErr.Stack) > 1 {
+		fmt.Fprint(os.Stderr, runtimeErr.StackTrace())
+	}
+}
+
+func replCommand(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	engineName := fs.String("engine", "tree", "engine running the input, tree or vm")
+	color := fs.String("color", "auto", "color diagnostics, auto, always or never")
+	path := searchPathFlag(fs)
+	fs.Parse(args)
+
+	engine, err := eval.ParseEngine(*engineName)
+	var renderer *parse.Renderer
+	if err == nil {
+		renderer, err = newRenderer(sourceFlags{color: color}, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	s := newSession(engine, searchPath(*path), os.Stdout, renderer.Color)
+	s.loadHistory(historyPath())
+	s.run(os.Stdin)
+	return 0
+}
+
+func checkCommand(args []string) int {
+	fs, flags := newFlagSet("check", true)
+	path := searchPathFlag(fs)
+	fs.Parse(args)
+
+	name, src, err := readSource(fs, flags)
+	if err == nil {
+		err = checkFormat(flags)
+	}
+	var renderer *parse.Renderer
+	if err == nil {
+		renderer, err = newRenderer(flags, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	parser := parse.NewParser(name, src)
+	tree, err := parser.Parse()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// type errors of a tree with syntax errors would be misleading
+	loader := module.NewLoader(searchPath(*path))
+	if !parser.Errors.HasErrors() {
+		checker := check.NewChecker(&parser.Errors)
+		checker.Loader = loader
+		checker.Check(tree)
+	}
+
+	if *flags.format == "json" {
+		if err := writeJSON(parser.Errors.GetErrors()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		renderer.AddSource(name, src)
+		addModuleSources(renderer, loader)
+		renderer.RenderAll(os.Stdout, parser.Errors.GetErrors())
+	}
+
+	if parser.Errors.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+func tokensCommand(args []string) int {
+	fs, flags := newFlagSet("tokens", true)
+	fs.Parse(args)
+
+	name, src, err := readSource(fs, flags)
+	if err == nil {
+		err = checkFormat(flags)
+	}
+	var renderer *parse.Renderer
+	if err == nil {
+		renderer, err = newRenderer(flags, os.Stderr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	tokens, errs := parse.Tokenize(name, src)
+	if *flags.format == "json" {
+		err = writeJSON(tokens)
+	} else {
+		for _, token := range tokens {
+			start := token.Loc.Start
+			fmt.Printf("%d:%d\t%s\t%q\n", start.Line+1, start.Col+1, token.Kind, token.Val)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	renderer.AddSource(name, src)
+	renderer.RenderAll(os.Stderr, errs)
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func astCommand(args []string) int {
+	fs, flags := newFlagSet("ast", true)
+	fs.Parse(args)
+
+	name, src, err := readSource(fs, flags)
+	if err == nil {
+		err = checkFormat(flags)
+	}
+	var renderer *parse.Renderer
+	if err == nil {
+		renderer, err = newRenderer(flags, os.Stderr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	tree, ok := parseSource(name, src, renderer)
+	if tree == nil {
+		return 1
+	}
+
+	if *flags.format == "json" {
+		err = writeJSON(parse.ToJSON(tree.Root))
+	} else {
+		err = parse.Fprint(os.Stdout, tree.Root)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// fmtCommand formats palm files, the formatted source is printed unless -w or -d is given
+func fmtCommand(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: palm fmt [flags] [files or directories]")
+		fmt.Fprintln(fs.Output(), "Without files the standard input is formatted to the standard output.")
+		fs.PrintDefaults()
+	}
+	write := fs.Bool("w", false, "write the result to the file instead of printing it")
+	showDiff := fs.Bool("d", false, "print diffs instead of the formatted source")
+	fs.Parse(args)
+
+	renderer := parse.NewRenderer(false)
+	if fs.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		tree, ok := parseSource("<stdin>", string(src), renderer)
+		if !ok {
+			return 1
+		}
+		fmt.Print(parse.Format(tree))
+		return 0
+	}
+
+	files, err := palmFiles(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	exitCode := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+			continue
+		}
+		tree, ok := parseSource(file, string(src), renderer)
+		if !ok {
+			exitCode = 1
+			continue
+		}
+
+		formatted := parse.Format(tree)
+		switch {
+		case *showDiff || *write:
+			if *showDiff {
+				fmt.Print(unifiedDiff(file+".orig", file, string(src), formatted))
+			}
+			if *write && formatted != string(src) {
+				err = os.WriteFile(file, []byte(formatted), 0o644)
+			}
+		default:
+			fmt.Print(formatted)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// lspCommand serves the language server protocol on the standard input and output
+func lspCommand(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: palm lsp [flags]")
+		fmt.Fprintln(fs.Output(), "The server reads requests from the standard input and writes responses to the standard output.")
+		fs.PrintDefaults()
+	}
+	logFile := fs.String("log", "", "append the messages of the server to the file")
+	fs.Parse(args)
+
+	server := lsp.NewServer(os.Stdin, os.Stdout)
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		server.Log = f
+	}
+	if err := server.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, "palm lsp:", err)
+		return 1
+	}
+	return 0
+}
+
+// docCommand writes the reference of the standard library, stdlib.md is generated by it
+//
+//go:generate go run . doc -o stdlib.md
+func docCommand(args []string) int {
+	fs := flag.NewFlagSet("doc", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: palm doc [flags]")
+		fmt.Fprintln(fs.Output(), "Doc writes the reference of the standard library as markdown.")
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "write the reference to the file instead of the standard output")
+	fs.Parse(args)
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := eval.WriteReference(out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// difftestCommand runs palm programs with the tree walker and the vm and reports programs
+// whose results or errors differ
+func difftestCommand(args []string) int {
+	fs := flag.NewFlagSet("difftest", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: palm difftest [flags] [files or directories]")
+		fs.PrintDefaults()
+	}
+	showTime := fs.Bool("time", false, "print the run time of both engines")
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"examples"}
+	}
+	files, err := palmFiles(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	renderer := parse.NewRenderer(false)
+	failed := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		tree, ok := parseSource(file, string(src), renderer)
+		if !ok {
+			fmt.Printf("FAIL\t%s\n", file)
+			failed++
+			continue
+		}
+
+		treeResult, treeTime := runEngine(eval.TreeWalker, file, tree)
+		vmResult, vmTime := runEngine(eval.BytecodeVM, file, tree)
+		if treeResult != vmResult {
+			fmt.Printf("FAIL\t%s\n\ttree: %s\n\tvm:   %s\n", file, treeResult, vmResult)
+			failed++
+			continue
+		}
+		if *showTime {
+			fmt.Printf("ok\t%s\ttree %s\tvm %s\n", file, treeTime, vmTime)
+		} else {
+			fmt.Printf("ok\t%s\n", file)
+		}
+	}
+
+	fmt.Printf("%d programs, %d failed\n", len(files), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// palmFiles returns the given files and the .pd files of the given directories
+func palmFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.pd"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// runEngine runs the tree in a new global scope and describes the outcome, errors are described
+// with their stack trace
+func runEngine(engine eval.Engine, name string, tree *parse.SyntaxTree) (string, time.Duration) {
+	start := time.Now()
+	result, err := eval.Run(context.Background(), engine, name, tree, eval.NewGlobalScope(), eval.Limits{})
+	elapsed := time.Since(start)
+
+	if err == nil {
+		return fmt.Sprint(result), elapsed
+	}
+	var runtimeErr *eval.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return "error " + err.Error() + "\n" + runtimeErr.StackTrace(), elapsed
+	}
+	return "error " + err.Error(), elapsed
+}
@@ -0,0 +1,411 @@
+// Package debug implements a debugger for palm programs run by the tree walker, with line breakpoints,
+// stepping, the scopes of the paused program and watch expressions, and a Debug Adapter Protocol server.
+package debug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myProgrammingLanguage/eval"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrTerminated is the error of programs terminated by the debugger
+var ErrTerminated = errors.New("program terminated by the debugger")
+
+// EventKind is the kind of the events of the debugger
+type EventKind int
+
+const (
+	// Stopped is sent when the program pauses, it waits for a command of the frontend
+	Stopped EventKind = iota
+	// Exited is sent when the program ends, it is the last event
+	Exited
+)
+
+// Event is sent by the debugger to its frontend
+type Event struct {
+	Kind EventKind
+	// Reason is why the program stopped: entry, breakpoint, step or pause
+	Reason string
+	// Step is the position the program stopped at
+	Step *eval.Step
+	// Result and Err are the outcome of the program for Exited events
+	Result any
+	Err    error
+}
+
+// action is the command a paused program is resumed with
+type action int
+
+const (
+	actionContinue action = iota
+	actionStepIn
+	actionStepOver
+	actionStepOut
+	actionTerminate
+)
+
+// position is the line of a node and the call depth it is visited at, lines start at 1
+type position struct {
+	file  string
+	line  int
+	depth int
+}
+
+// Debugger runs a program on the tree walker and pauses it at breakpoints and after steps. The program
+// runs in its own goroutine, the frontend receives its events from Events and controls it with the
+// methods of the debugger. The step of a paused program may be inspected until the program is resumed.
+type Debugger struct {
+	importer *eval.Importer
+	events   chan Event
+	resume   chan action
+	cancel   context.CancelFunc
+
+	mu sync.Mutex
+	// breakpoints holds the lines with breakpoints by absolute file path
+	breakpoints map[string]map[int]bool
+	// code holds the lines where nodes start by absolute file path, breakpoints can only be set on them
+	code map[string]map[int]bool
+	// paths caches the absolute paths of the file names of the nodes
+	paths map[string]string
+	entry bool
+	pause bool
+	// terminated is set by Terminate, the program stops at the next node
+	terminated bool
+	// action is the action the program was resumed with at the position origin
+	action action
+	origin position
+	// lines holds the position of the last visited node of every call depth
+	lines []position
+	// step is the step the program is paused at, it is nil while the program runs
+	step *eval.Step
+	at   position
+}
+
+// New returns a debugger running programs with imports resolved by the loader
+func New(loader *module.Loader) *Debugger {
+	return &Debugger{
+		importer:    eval.NewImporter(loader, eval.TreeWalker, eval.Limits{}),
+		events:      make(chan Event),
+		resume:      make(chan action, 1),
+		breakpoints: map[string]map[int]bool{},
+		code:        map[string]map[int]bool{},
+		paths:       map[string]string{},
+	}
+}
+
+// Events returns the channel the events of the program are sent to, the frontend must receive them
+func (d *Debugger) Events() <-chan Event {
+	return d.events
+}
+
+// Breakpoint is a breakpoint requested by the frontend, Message tells why it isn't verified
+type Breakpoint struct {
+	Line     int
+	Verified bool
+	Message  string
+}
+
+// SetBreakpoints replaces the breakpoints of the file with the given lines. Breakpoints are only set on
+// lines where code starts, the others are returned unverified and never stop the program.
+func (d *Debugger) SetBreakpoints(file string, lines []int) []Breakpoint {
+	code, err := d.codeLines(file)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	path := d.path(file)
+	d.breakpoints[path] = map[int]bool{}
+	breakpoints := make([]Breakpoint, len(lines))
+	for i, line := range lines {
+		breakpoints[i] = Breakpoint{Line: line, Verified: err == nil && code[line]}
+		switch {
+		case err != nil:
+			breakpoints[i].Message = err.Error()
+		case !code[line]:
+			breakpoints[i].Message = fmt.Sprintf("no code at line %d", line)
+		default:
+			d.breakpoints[path][line] = true
+		}
+	}
+	return breakpoints
+}
+
+// codeLines returns the lines of the file where the program can stop, the lines where nodes other than
+// programs, blocks and types start
+func (d *Debugger) codeLines(file string) (map[int]bool, error) {
+	d.mu.Lock()
+	path := d.path(file)
+	code, ok := d.code[path]
+	d.mu.Unlock()
+	if ok {
+		return code, nil
+	}
+
+	// the file is read again instead of loaded by the loader, which the running program uses
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parser := parse.NewParser(path, string(src))
+	tree, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if parser.Errors.HasErrors() {
+		return nil, fmt.Errorf("%s has syntax errors", file)
+	}
+	code = map[int]bool{}
+	parse.Inspect(tree.Root, func(node parse.Node) bool {
+		switch node.Kind() {
+		case parse.NodeProgram, parse.NodeBlockStatement:
+		case parse.NodeType:
+			return false
+		default:
+			code[parse.FirstToken(node).Loc.Start.Line+1] = true
+		}
+		return true
+	})
+
+	d.mu.Lock()
+	d.code[path] = code
+	d.mu.Unlock()
+	return code, nil
+}
+
+// Breakpoints returns the sorted lines with breakpoints of the file
+func (d *Debugger) Breakpoints(file string) []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var lines []int
+	for line := range d.breakpoints[d.path(file)] {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Path returns the absolute path the breakpoints of the file are set for
+func (d *Debugger) Path(file string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.path(file)
+}
+
+// path returns the absolute path of the file, names which aren't files are returned as they are
+func (d *Debugger) path(file string) string {
+	if path, ok := d.paths[file]; ok {
+		return path
+	}
+	path, err := filepath.Abs(file)
+	if err != nil {
+		path = file
+	}
+	d.paths[file] = path
+	return path
+}
+
+// Start runs the tree in the scope in a new goroutine, with stopOnEntry the program stops before its
+// first node
+func (d *Debugger) Start(ctx context.Context, name string, tree *parse.SyntaxTree, scope *parse.Scope, stopOnEntry bool) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.entry = stopOnEntry
+	d.importer.SetHook(d.hook)
+	go func() {
+		result, err := d.importer.Run(ctx, name, tree, scope)
+		d.mu.Lock()
+		if err != nil && d.terminated {
+			err = ErrTerminated
+		}
+		d.mu.Unlock()
+		d.cancel()
+		d.events <- Event{Kind: Exited, Result: result, Err: err}
+	}()
+}
+
+// Paused returns the step the program is paused at or nil if it is running
+func (d *Debugger) Paused() *eval.Step {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.step
+}
+
+// Continue resumes the paused program until the next breakpoint
+func (d *Debugger) Continue() bool {
+	return d.resumeWith(actionContinue)
+}
+
+// StepIn resumes the paused program until it reaches another line, calls on the line are entered
+func (d *Debugger) StepIn() bool {
+	return d.resumeWith(actionStepIn)
+}
+
+// StepOver resumes the paused program until it reaches another line of the same function or returns
+func (d *Debugger) StepOver() bool {
+	return d.resumeWith(actionStepOver)
+}
+
+// StepOut resumes the paused program until the current function returns
+func (d *Debugger) StepOut() bool {
+	return d.resumeWith(actionStepOut)
+}
+
+// Pause stops the running program before the next node
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pause = true
+}
+
+// Terminate stops the program, it ends with ErrTerminated
+func (d *Debugger) Terminate() {
+	d.mu.Lock()
+	d.terminated = true
+	d.mu.Unlock()
+	if d.cancel != nil {
+		// the context stops builtins like time.sleep
+		d.cancel()
+	}
+	d.resumeWith(actionTerminate)
+}
+
+// resumeWith resumes the paused program, it returns false if the program is not paused
+func (d *Debugger) resumeWith(a action) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.step == nil {
+		return false
+	}
+	d.step = nil
+	d.action, d.origin = a, d.at
+	d.resume <- a
+	return true
+}
+
+// hook is called by the tree walker before every node, it blocks while the program is paused
+func (d *Debugger) hook(step *eval.Step) error {
+	// programs and blocks only hold statements, the program stops at the statements instead
+	if kind := step.Node.Kind(); kind == parse.NodeProgram || kind == parse.NodeBlockStatement {
+		return nil
+	}
+
+	loc := step.Loc().Start
+	d.mu.Lock()
+	if d.terminated {
+		d.mu.Unlock()
+		return ErrTerminated
+	}
+	pos := position{file: d.path(loc.Filename), line: loc.Line + 1, depth: step.Depth}
+	reason := d.stopReason(pos)
+	if reason == "" {
+		d.mu.Unlock()
+		return nil
+	}
+	d.step, d.at = step, pos
+	d.mu.Unlock()
+
+	d.events <- Event{Kind: Stopped, Reason: reason, Step: step}
+	if <-d.resume == actionTerminate {
+		return ErrTerminated
+	}
+	return nil
+}
+
+// stopReason returns why the program stops at the position or "" if it doesn't stop
+func (d *Debugger) stopReason(pos position) string {
+	// the line of the depth is kept while calls on the line run, so that returning from them into the
+	// middle of the line doesn't count as a new line
+	for len(d.lines) <= pos.depth {
+		d.lines = append(d.lines, position{})
+	}
+	d.lines = d.lines[:pos.depth+1]
+	newLine := d.lines[pos.depth] != pos
+	d.lines[pos.depth] = pos
+
+	switch {
+	case d.entry:
+		d.entry = false
+		return "entry"
+	case d.pause:
+		d.pause = false
+		return "pause"
+	case newLine && d.breakpoints[pos.file][pos.line]:
+		return "breakpoint"
+	}
+
+	otherLine := pos.file != d.origin.file || pos.line != d.origin.line
+	switch d.action {
+	case actionStepIn:
+		if pos.depth != d.origin.depth || otherLine {
+			return "step"
+		}
+	case actionStepOver:
+		if pos.depth < d.origin.depth || pos.depth == d.origin.depth && otherLine {
+			return "step"
+		}
+	case actionStepOut:
+		if pos.depth < d.origin.depth {
+			return "step"
+		}
+	}
+	return ""
+}
+
+// Scope is a scope of a scope chain with its variables
+type Scope struct {
+	Name string
+	// Vars holds the variables of the scope sorted by name
+	Vars []Variable
+}
+
+// Variable is a variable of a scope
+type Variable struct {
+	Name  string
+	Value any
+}
+
+// Scopes returns the scope chain of the frame, the builtins of the outermost scope are left out. The
+// scopes of the function up to its parameters are merged into its locals, inner variables shadow outer ones.
+func Scopes(frame eval.StackFrame) []Scope {
+	var scopes []Scope
+	inFunction := true
+	for scope := frame.Scope; scope != nil && scope.Parent() != nil; scope = scope.Parent() {
+		name := "Enclosing"
+		switch {
+		case scope.Parent().Parent() == nil:
+			name = "Globals"
+		case inFunction:
+			name = "Locals"
+		}
+		if scope == frame.Locals {
+			inFunction = false
+		}
+
+		if len(scopes) == 0 || name != "Locals" {
+			scopes = append(scopes, Scope{Name: name})
+		}
+		s := &scopes[len(scopes)-1]
+		for _, variable := range scope.Names() {
+			if name == "Locals" && s.has(variable) {
+				continue
+			}
+			val, _ := scope.ResolveLocal(variable)
+			s.Vars = append(s.Vars, Variable{Name: variable, Value: val})
+		}
+		sort.Slice(s.Vars, func(i, j int) bool { return s.Vars[i].Name < s.Vars[j].Name })
+	}
+	return scopes
+}
+
+func (s *Scope) has(name string) bool {
+	for _, v := range s.Vars {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
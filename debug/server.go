@@ -0,0 +1,515 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"myProgrammingLanguage/check"
+	"myProgrammingLanguage/eval"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"myProgrammingLanguage/transport"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// threadID is the id of the only thread of palm programs
+const threadID = 1
+
+// Server serves the Debug Adapter Protocol for one client and debugs one program. The program is launched by
+// the launch request and starts once the client is done configuring breakpoints.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	loader   *module.Loader
+	debugger *Debugger
+	renderer *parse.Renderer
+	// writeMu orders the messages, events of the program are sent from another goroutine
+	writeMu sync.Mutex
+	seq     int
+
+	mu      sync.Mutex
+	launch  *launchArguments
+	tree    *parse.SyntaxTree
+	started bool
+	// configured is set by the configurationDone request, the program starts when it is launched too
+	configured bool
+	// handles holds the scopes and values of the variable references of the paused program, a reference is an
+	// index plus one. The references are valid until the program resumes.
+	handles []any
+	exited  chan struct{}
+	// CaptureOutput makes the server replace os.Stdout while the program runs, the output of the program is
+	// sent to the client as output events
+	CaptureOutput bool
+	// Log receives the messages of the server, it is io.Discard by default
+	Log io.Writer
+}
+
+// NewServer returns a server which loads the imports of programs from the search path
+func NewServer(in io.Reader, out io.Writer, searchPath []string) *Server {
+	loader := module.NewLoader(searchPath)
+	return &Server{
+		in:       bufio.NewReader(in),
+		out:      out,
+		loader:   loader,
+		debugger: New(loader),
+		renderer: parse.NewRenderer(false),
+		exited:   make(chan struct{}),
+		Log:      io.Discard,
+	}
+}
+
+// Serve handles requests until the disconnect request or the end of the input. A running program is
+// terminated before Serve returns.
+func (s *Server) Serve() error {
+	for {
+		req, err := readRequest(s.in)
+		if err != nil {
+			s.stop()
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		body, err := s.dispatch(req)
+		resp := response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+		if err != nil {
+			fmt.Fprintf(s.Log, "%s: %s\n", req.Command, err)
+			resp.Message = err.Error()
+		}
+		s.send(&resp)
+
+		// the events of the program follow the response of the request which runs it
+		switch req.Command {
+		case "initialize":
+			// the client sends its breakpoints once the server is initialized
+			s.sendEvent("initialized", nil)
+		case "launch", "configurationDone":
+			s.start()
+		case "continue":
+			s.resume(s.debugger.Continue)
+		case "next":
+			s.resume(s.debugger.StepOver)
+		case "stepIn":
+			s.resume(s.debugger.StepIn)
+		case "stepOut":
+			s.resume(s.debugger.StepOut)
+		case "pause":
+			s.debugger.Pause()
+		case "terminate":
+			s.debugger.Terminate()
+		case "disconnect":
+			s.stop()
+			return nil
+		}
+	}
+}
+
+// Output sends the text to the client as an output event of the category, like stdout or stderr
+func (s *Server) Output(category, text string) {
+	s.sendEvent("output", outputEventBody{Category: category, Output: text})
+}
+
+func (s *Server) send(msg any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+	if err := transport.WriteMessage(s.out, msg); err != nil {
+		fmt.Fprintln(s.Log, err)
+	}
+}
+
+func (s *Server) sendEvent(name string, body any) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// dispatch runs the handler of the command and returns the body of its response
+func (s *Server) dispatch(req *request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}, nil
+	case "launch":
+		var args launchArguments
+		if err := unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.load(&args)
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if err := unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		lines := make([]int, len(args.Breakpoints))
+		for i, bp := range args.Breakpoints {
+			lines[i] = bp.Line
+		}
+		breakpoints := make([]breakpoint, 0, len(lines))
+		for _, bp := range s.debugger.SetBreakpoints(args.Source.Path, lines) {
+			breakpoints = append(breakpoints, breakpoint{Verified: bp.Verified, Message: bp.Message, Line: bp.Line, Source: &args.Source})
+		}
+		return map[string]any{"breakpoints": breakpoints}, nil
+	case "configurationDone":
+		s.mu.Lock()
+		s.configured = true
+		s.mu.Unlock()
+		return nil, nil
+	case "threads":
+		return map[string]any{"threads": []thread{{ID: threadID, Name: "main"}}}, nil
+
+	case "stackTrace":
+		var args stackTraceArguments
+		if err := unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.stackTrace(args)
+	case "scopes":
+		var args scopesArguments
+		if err := unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args.FrameID)
+	case "variables":
+		var args variablesArguments
+		if err := unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+	case "evaluate":
+		var args evaluateArguments
+		if err := unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+
+	case "continue":
+		return map[string]any{"allThreadsContinued": true}, nil
+	case "next", "stepIn", "stepOut", "pause", "disconnect", "terminate":
+		// they are run by Serve once the response is sent
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", req.Command)
+}
+
+func unmarshal(args json.RawMessage, v any) error {
+	if len(args) == 0 {
+		return nil
+	}
+	return json.Unmarshal(args, v)
+}
+
+// load parses and checks the program of the launch request, errors are reported in the response
+func (s *Server) load(args *launchArguments) error {
+	src, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+	s.renderer.AddSource(args.Program, string(src))
+	parser := parse.NewParser(args.Program, string(src))
+	tree, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+	if parser.Errors.HasErrors() {
+		return s.diagnostics(parser.Errors.GetErrors())
+	}
+
+	checker := check.NewChecker(parse.NewErrorContainer())
+	checker.Loader = s.loader
+	checker.Check(tree)
+	s.addModuleSources()
+	if checker.Errors.HasErrors() {
+		return s.diagnostics(checker.Errors.GetErrors())
+	}
+
+	s.mu.Lock()
+	s.launch, s.tree = args, tree
+	s.mu.Unlock()
+	return nil
+}
+
+// diagnostics returns an error holding the rendered diagnostics
+func (s *Server) diagnostics(errs []parse.Err) error {
+	builder := strings.Builder{}
+	s.renderer.RenderAll(&builder, errs)
+	return errors.New(strings.TrimRight(builder.String(), "\n"))
+}
+
+func (s *Server) addModuleSources() {
+	for _, file := range s.loader.Files() {
+		s.renderer.AddSource(file.Path, file.Src)
+	}
+}
+
+// start starts the program once it is launched and configured and sends the events of the debugger
+func (s *Server) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.launch == nil || !s.configured {
+		return
+	}
+	s.started = true
+	release := s.captureOutput()
+	s.debugger.Start(context.Background(), s.launch.Program, s.tree, eval.NewGlobalScope(), s.launch.StopOnEntry)
+
+	go func() {
+		defer close(s.exited)
+		for ev := range s.debugger.Events() {
+			if ev.Kind == Stopped {
+				s.mu.Lock()
+				s.handles = nil
+				s.mu.Unlock()
+				s.sendEvent("stopped", stoppedEventBody{Reason: ev.Reason, ThreadID: threadID, AllThreadsStopped: true})
+				continue
+			}
+
+			// the output of the program is sent before it exits
+			release()
+			exitCode := 0
+			if ev.Err != nil {
+				exitCode = 1
+				if !errors.Is(ev.Err, ErrTerminated) {
+					s.Output("stderr", s.runtimeError(ev.Err))
+				}
+			}
+			s.sendEvent("exited", exitedEventBody{ExitCode: exitCode})
+			s.sendEvent("terminated", nil)
+			return
+		}
+	}()
+}
+
+// captureOutput replaces os.Stdout with a pipe forwarded to the client if CaptureOutput is set. The
+// returned function restores os.Stdout and waits until the output is sent.
+func (s *Server) captureOutput() func() {
+	if !s.CaptureOutput {
+		return func() {}
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintln(s.Log, err)
+		return func() {}
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				s.Output("stdout", string(buf[:n]))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return func() {
+		os.Stdout = stdout
+		w.Close()
+		<-done
+		r.Close()
+	}
+}
+
+// stop terminates the running program and waits until it exits
+func (s *Server) stop() {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		s.debugger.Terminate()
+		<-s.exited
+	}
+}
+
+// runtimeError renders the error with the stack trace of runtime errors
+func (s *Server) runtimeError(err error) string {
+	builder := strings.Builder{}
+	var runtimeErr *eval.RuntimeError
+	var syntaxErr *eval.SyntaxError
+	s.addModuleSources()
+	switch {
+	case errors.As(err, &runtimeErr):
+		s.renderer.Render(&builder, runtimeErr.Diagnostic())
+		builder.WriteString(runtimeErr.StackTrace())
+	case errors.As(err, &syntaxErr):
+		s.renderer.RenderAll(&builder, syntaxErr.Errors)
+	default:
+		fmt.Fprintln(&builder, err)
+	}
+	return builder.String()
+}
+
+// resume resumes the paused program with the method of the debugger
+func (s *Server) resume(method func() bool) {
+	if !method() {
+		fmt.Fprintln(s.Log, "the program is not paused")
+	}
+}
+
+// paused returns the step of the paused program
+func (s *Server) paused() (*eval.Step, error) {
+	step := s.debugger.Paused()
+	if step == nil {
+		return nil, errors.New("the program is not paused")
+	}
+	return step, nil
+}
+
+// frame returns the frame of the paused program with the id, ids are indices starting from the innermost frame
+func (s *Server) frame(id int) (eval.StackFrame, error) {
+	step, err := s.paused()
+	if err != nil {
+		return eval.StackFrame{}, err
+	}
+	frames := step.Frames()
+	if id < 0 || id >= len(frames) {
+		return eval.StackFrame{}, fmt.Errorf("unknown frame %d", id)
+	}
+	return frames[id], nil
+}
+
+func (s *Server) stackTrace(args stackTraceArguments) (any, error) {
+	step, err := s.paused()
+	if err != nil {
+		return nil, err
+	}
+	frames := step.Frames()
+	stack := []stackFrame{}
+	for i, frame := range frames {
+		if i < args.StartFrame || args.Levels > 0 && len(stack) == args.Levels {
+			continue
+		}
+		start := frame.Loc.Start
+		stack = append(stack, stackFrame{
+			ID:     i,
+			Name:   frame.Function,
+			Source: &source{Name: filepath.Base(start.Filename), Path: s.debugger.Path(start.Filename)},
+			Line:   start.Line + 1,
+			Column: start.Col + 1,
+		})
+	}
+	return map[string]any{"stackFrames": stack, "totalFrames": len(frames)}, nil
+}
+
+func (s *Server) scopes(frameID int) (any, error) {
+	frame, err := s.frame(frameID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []scope{}
+	for _, sc := range Scopes(frame) {
+		scopes = append(scopes, scope{Name: sc.Name, VariablesReference: s.handle(sc)})
+	}
+	return map[string]any{"scopes": scopes}, nil
+}
+
+func (s *Server) variables(ref int) (any, error) {
+	if _, err := s.paused(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if ref < 1 || ref > len(s.handles) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("unknown variables reference %d", ref)
+	}
+	handle := s.handles[ref-1]
+	s.mu.Unlock()
+
+	variables := []variable{}
+	switch v := handle.(type) {
+	case Scope:
+		for _, val := range v.Vars {
+			variables = append(variables, s.variable(val.Name, val.Value))
+		}
+	case *eval.Array:
+		for i, elem := range v.Elements {
+			variables = append(variables, s.variable(fmt.Sprintf("[%d]", i), elem))
+		}
+	case *eval.Struct:
+		for i, field := range v.Type.Fields {
+			variables = append(variables, s.variable(field.Name, v.Fields[i]))
+		}
+	}
+	return map[string]any{"variables": variables}, nil
+}
+
+// variable returns the variable with the value, arrays and structs get a reference to their elements
+func (s *Server) variable(name string, val any) variable {
+	return variable{Name: name, Value: eval.FormatValue(val), VariablesReference: s.valueHandle(val)}
+}
+
+func (s *Server) evaluate(args evaluateArguments) (any, error) {
+	step, err := s.paused()
+	if err != nil {
+		return nil, err
+	}
+	sc := step.Scope
+	if args.FrameID != nil {
+		frame, err := s.frame(*args.FrameID)
+		if err != nil {
+			return nil, err
+		}
+		sc = frame.Scope
+	}
+	val, err := step.Eval(args.Expression, sc)
+	if err != nil {
+		return nil, errors.New(ErrorMessage(err))
+	}
+	return map[string]any{"result": eval.FormatValue(val), "variablesReference": s.valueHandle(val)}, nil
+}
+
+// handle returns a new variables reference for the scope or value
+func (s *Server) handle(v any) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handles = append(s.handles, v)
+	return len(s.handles)
+}
+
+// valueHandle returns a reference for the elements of arrays and structs and 0 for other values
+func (s *Server) valueHandle(val any) int {
+	switch v := val.(type) {
+	case *eval.Array:
+		if len(v.Elements) > 0 {
+			return s.handle(v)
+		}
+	case *eval.Struct:
+		if v != nil && len(v.Fields) > 0 {
+			return s.handle(v)
+		}
+	}
+	return 0
+}
+
+// ErrorMessage returns the message of an error of a watch expression without its location
+func ErrorMessage(err error) string {
+	var runtimeErr *eval.RuntimeError
+	var syntaxErr *eval.SyntaxError
+	switch {
+	case errors.As(err, &runtimeErr):
+		return runtimeErr.Msg
+	case errors.As(err, &syntaxErr) && len(syntaxErr.Errors) > 0:
+		return syntaxErr.Errors[0].Msg
+	}
+	return err.Error()
+}
@@ -0,0 +1,210 @@
+package debug
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"myProgrammingLanguage/transport"
+)
+
+// client sends requests to a server over pipes and checks the order of its responses and events
+type client struct {
+	t   *testing.T
+	in  io.WriteCloser
+	out *bufio.Reader
+	seq int
+}
+
+type clientMessage struct {
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func newClient(t *testing.T) (*client, chan error) {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(inReader, outWriter, nil).Serve()
+		outWriter.Close()
+	}()
+	return &client{t: t, in: inWriter, out: bufio.NewReader(outReader)}, done
+}
+
+// request sends a request and returns the body of its response, which must be the next message
+func (c *client) request(command string, args any) json.RawMessage {
+	c.t.Helper()
+	msg := c.response(command, args)
+	if !msg.Success {
+		c.t.Fatalf("%s: %s", command, msg.Message)
+	}
+	return msg.Body
+}
+
+// requestError sends a request which must fail and returns the message of its response
+func (c *client) requestError(command string, args any) string {
+	c.t.Helper()
+	msg := c.response(command, args)
+	if msg.Success {
+		c.t.Fatalf("%s: got a successful response, want an error", command)
+	}
+	return msg.Message
+}
+
+func (c *client) response(command string, args any) clientMessage {
+	c.t.Helper()
+	c.seq++
+	if err := transport.WriteMessage(c.in, map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args}); err != nil {
+		c.t.Fatal(err)
+	}
+	msg := c.read()
+	if msg.Type != "response" || msg.RequestSeq != c.seq {
+		c.t.Fatalf("%s: got %s %s%s before the response", command, msg.Type, msg.Command, msg.Event)
+	}
+	return msg
+}
+
+// event reads the next message, which must be the event
+func (c *client) event(name string) json.RawMessage {
+	c.t.Helper()
+	msg := c.read()
+	if msg.Type != "event" || msg.Event != name {
+		c.t.Fatalf("got %s %s%s, want the %s event", msg.Type, msg.Command, msg.Event, name)
+	}
+	return msg.Body
+}
+
+func (c *client) read() clientMessage {
+	c.t.Helper()
+	body, err := transport.ReadMessage(c.out)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var msg clientMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+func decode(t *testing.T, body json.RawMessage, v any) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const testProgram = `g := 10
+fn add(int a, int b) int {
+    s := a + b
+    if s > 0 {
+        t := s * 2
+        return t + g
+    }
+    return s
+}
+r := add(1, 2)
+r
+`
+
+func TestServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.pd")
+	if err := os.WriteFile(path, []byte(testProgram), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, done := newClient(t)
+
+	c.request("initialize", map[string]any{"adapterID": "palm"})
+	c.event("initialized")
+	c.request("launch", launchArguments{Program: path, StopOnEntry: true})
+	// breakpoints are only verified on lines with code
+	var breakpoints struct{ Breakpoints []breakpoint }
+	decode(t, c.request("setBreakpoints", setBreakpointsArguments{Source: source{Path: path}, Breakpoints: []sourceBreakpoint{{Line: 6}, {Line: 7}, {Line: 99}}}), &breakpoints)
+	var verified []string
+	for _, bp := range breakpoints.Breakpoints {
+		verified = append(verified, fmt.Sprintf("%d:%v", bp.Line, bp.Verified))
+	}
+	if got := strings.Join(verified, " "); got != "6:true 7:false 99:false" || breakpoints.Breakpoints[1].Message != "no code at line 7" {
+		t.Errorf("got breakpoints %+v, want only line 6 verified", breakpoints.Breakpoints)
+	}
+	missing := filepath.Join(filepath.Dir(path), "missing.pd")
+	decode(t, c.request("setBreakpoints", setBreakpointsArguments{Source: source{Path: missing}, Breakpoints: []sourceBreakpoint{{Line: 1}}}), &breakpoints)
+	if len(breakpoints.Breakpoints) != 1 || breakpoints.Breakpoints[0].Verified {
+		t.Errorf("got breakpoints %+v in a missing file, want them unverified", breakpoints.Breakpoints)
+	}
+	c.request("configurationDone", nil)
+	var stopped stoppedEventBody
+	decode(t, c.event("stopped"), &stopped)
+	if stopped.Reason != "entry" {
+		t.Errorf("got stopped reason %q, want entry", stopped.Reason)
+	}
+
+	// the responses of the requests which resume the program precede its events
+	for _, command := range []string{"next", "stepIn", "continue"} {
+		c.request(command, map[string]any{"threadId": threadID})
+		decode(t, c.event("stopped"), &stopped)
+	}
+	if stopped.Reason != "breakpoint" {
+		t.Errorf("got stopped reason %q, want breakpoint", stopped.Reason)
+	}
+
+	var scopes struct{ Scopes []scope }
+	decode(t, c.request("scopes", scopesArguments{FrameID: 0}), &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("got scopes %+v, want Locals and Globals", scopes.Scopes)
+	}
+	var variables struct{ Variables []variable }
+	decode(t, c.request("variables", variablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}), &variables)
+	var locals []string
+	for _, v := range variables.Variables {
+		locals = append(locals, v.Name+"="+v.Value)
+	}
+	if got := strings.Join(locals, " "); got != "a=1 b=2 s=3 t=6" {
+		t.Errorf("got locals %s, want the parameters and the variables of the function", got)
+	}
+
+	// evaluating expressions doesn't change the program
+	for _, expression := range []string{"g = 0", "t := 1", "fn() { s += 1 }()"} {
+		if msg := c.requestError("evaluate", evaluateArguments{Expression: expression}); !strings.HasPrefix(msg, "watch expressions") {
+			t.Errorf("%s: got %q, want an error", expression, msg)
+		}
+	}
+	var result struct{ Result string }
+	decode(t, c.request("evaluate", evaluateArguments{Expression: "s + t + g"}), &result)
+	if result.Result != "19" {
+		t.Errorf("got %s, want 19", result.Result)
+	}
+
+	c.request("stepOut", map[string]any{"threadId": threadID})
+	c.event("stopped")
+	c.request("continue", map[string]any{"threadId": threadID})
+	c.event("exited")
+	c.event("terminated")
+	c.request("disconnect", nil)
+	if err := <-done; err != nil {
+		t.Errorf("disconnect: %v", err)
+	}
+}
+
+func TestServerInvalidContentLength(t *testing.T) {
+	for _, header := range []string{"Content-Length: -1\r\n\r\n", "Content-Length: 1099511627776\r\n\r\n"} {
+		c, done := newClient(t)
+		if _, err := io.WriteString(c.in, header); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err == nil {
+			t.Errorf("%q: got no error", header)
+		}
+	}
+}
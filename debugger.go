@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"myProgrammingLanguage/check"
+	"myProgrammingLanguage/debug"
+	"myProgrammingLanguage/eval"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const debugHelp = `The program stops before its first statement, commands control it while it is stopped.
+An empty line repeats the last command.
+
+	break [file:]line   set a breakpoint, b for short
+	clear [file:]line   remove a breakpoint
+	breakpoints         list the breakpoints
+	continue            run until the next breakpoint, c for short
+	step                run until another line, entering calls, s for short
+	next                run until another line of the function, n for short
+	out                 run until the function returns, o for short
+	stack               print the call stack, bt for short
+	frame n             select the frame n of the stack for vars and print
+	vars                print the scopes of the selected frame
+	print expr          evaluate the expression in the selected frame, p for short;
+	                    assignments are rejected but called functions may change variables
+	watch expr          evaluate the expression at every stop
+	unwatch n           remove the watch expression n
+	list                print the source around the current line, l for short
+	help                print this help
+	quit                terminate the program, q for short
+`
+
+// debugSession is the command line frontend of the debugger
+type debugSession struct {
+	debugger *debug.Debugger
+	loader   *module.Loader
+	renderer *parse.Renderer
+	out      io.Writer
+	// main is the file of the program, the default file of breakpoints
+	main string
+	// sources holds the lines of the program and its modules by file
+	sources map[string][]string
+	// files holds the files with breakpoints in the order they were set
+	files   []string
+	watches []string
+	// step is the step the program is stopped at and frame the index of the selected frame
+	step  *eval.Step
+	frame int
+	last  string
+}
+
+// debugCommand debugs a program on the command line or serves the Debug Adapter Protocol for editors
+func debugCommand(args []string) int {
+	fs := flag.NewFlagSet("debug", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: palm debug [flags] file")
+		fmt.Fprintln(fs.Output(), "       palm debug -dap [flags]")
+		fmt.Fprintln(fs.Output(), "Debug runs the program with the tree walker and stops it at breakpoints, type help for the commands.")
+		fs.PrintDefaults()
+	}
+	dap := fs.Bool("dap", false, "serve the Debug Adapter Protocol on standard input and output, the program is given by the launch request")
+	logFile := fs.String("log", "", "append the messages of the adapter to the file, only with -dap")
+	color := fs.String("color", "auto", "color diagnostics, auto, always or never")
+	path := searchPathFlag(fs)
+	fs.Parse(args)
+
+	if *dap {
+		return serveDAP(searchPath(*path), *logFile)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	name := fs.Arg(0)
+	src, err := os.ReadFile(name)
+	var renderer *parse.Renderer
+	if err == nil {
+		renderer, err = newRenderer(sourceFlags{color: color}, os.Stderr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	tree, ok := parseSource(name, string(src), renderer)
+	if !ok {
+		return 1
+	}
+	loader := module.NewLoader(searchPath(*path))
+	checker := check.NewChecker(parse.NewErrorContainer())
+	checker.Loader = loader
+	checker.Check(tree)
+	addModuleSources(renderer, loader)
+	if checker.Errors.HasErrors() {
+		renderer.RenderAll(os.Stderr, checker.Errors.GetErrors())
+		return 1
+	}
+
+	s := &debugSession{
+		debugger: debug.New(loader),
+		loader:   loader,
+		renderer: renderer,
+		out:      os.Stdout,
+		main:     name,
+		sources:  map[string][]string{name: strings.Split(string(src), "\n")},
+	}
+	s.debugger.Start(context.Background(), name, tree, eval.NewGlobalScope(), true)
+	return s.run(os.Stdin)
+}
+
+// serveDAP serves the Debug Adapter Protocol on the standard input and output. The output of the program
+// is sent to the client as output events and the program reads an empty standard input.
+func serveDAP(path []string, logFile string) int {
+	server := debug.NewServer(os.Stdin, os.Stdout, path)
+	server.CaptureOutput = true
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer stdin.Close()
+	os.Stdin = stdin
+
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		server.Log = f
+	}
+	if err := server.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, "palm debug:", err)
+		return 1
+	}
+	return 0
+}
+
+// run shows the stops of the program and reads commands from in until the program exits. The end of
+// the input terminates the program.
+func (s *debugSession) run(in io.Reader) int {
+	scanner := bufio.NewScanner(in)
+	for ev := range s.debugger.Events() {
+		if ev.Kind == debug.Exited {
+			return s.exited(ev)
+		}
+
+		s.step, s.frame = ev.Step, 0
+		s.stopped(ev.Reason)
+		for !s.prompt(scanner) {
+		}
+	}
+	return 0
+}
+
+func (s *debugSession) exited(ev debug.Event) int {
+	switch {
+	case errors.Is(ev.Err, debug.ErrTerminated):
+		fmt.Fprintln(s.out, "program terminated")
+	case ev.Err != nil:
+		addModuleSources(s.renderer, s.loader)
+		printRuntimeError(ev.Err, s.renderer)
+		return 1
+	default:
+		if ev.Result != nil {
+			fmt.Fprintln(s.out, ev.Result)
+		}
+		fmt.Fprintln(s.out, "program exited")
+	}
+	return 0
+}
+
+// stopped prints the position of the program and the values of the watch expressions
+func (s *debugSession) stopped(reason string) {
+	start := s.step.Loc().Start
+	frames := s.step.Frames()
+	fmt.Fprintf(s.out, "stopped at %s:%d in %s (%s)\n", start.Filename, start.Line+1, frames[0].Function, reason)
+	s.printLines(start.Filename, start.Line+1, 0)
+	for i, watch := range s.watches {
+		fmt.Fprintf(s.out, "%d: %s = %s\n", i+1, watch, s.eval(watch))
+	}
+}
+
+// prompt reads and runs a command, it returns true once the program is resumed
+func (s *debugSession) prompt(scanner *bufio.Scanner) bool {
+	fmt.Fprint(s.out, "(palm) ")
+	if !scanner.Scan() {
+		fmt.Fprintln(s.out)
+		s.debugger.Terminate()
+		return true
+	}
+
+	input := strings.TrimSpace(scanner.Text())
+	if input == "" {
+		input = s.last
+	}
+	s.last = input
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "":
+		return false
+	case "continue", "c":
+		return s.debugger.Continue()
+	case "step", "s":
+		return s.debugger.StepIn()
+	case "next", "n":
+		return s.debugger.StepOver()
+	case "out", "o":
+		return s.debugger.StepOut()
+	case "quit", "q":
+		s.debugger.Terminate()
+		return true
+
+	case "break", "b":
+		s.setBreakpoint(arg, true)
+	case "clear":
+		s.setBreakpoint(arg, false)
+	case "breakpoints":
+		for _, file := range s.files {
+			for _, line := range s.debugger.Breakpoints(file) {
+				fmt.Fprintf(s.out, "%s:%d\n", file, line)
+			}
+		}
+	case "stack", "bt":
+		for i, frame := range s.step.Frames() {
+			marker := " "
+			if i == s.frame {
+				marker = "*"
+			}
+			fmt.Fprintf(s.out, "%s %d %s\n", marker, i, frame)
+		}
+	case "frame":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(s.step.Frames()) {
+			fmt.Fprintf(s.out, "no frame %s, stack lists the frames\n", arg)
+			break
+		}
+		s.frame = n
+		fmt.Fprintln(s.out, s.step.Frames()[n])
+	case "vars":
+		for _, scope := range debug.Scopes(s.step.Frames()[s.frame]) {
+			fmt.Fprintf(s.out, "%s:\n", scope.Name)
+			for _, v := range scope.Vars {
+				fmt.Fprintf(s.out, "\t%s = %s\n", v.Name, eval.FormatValue(v.Value))
+			}
+		}
+	case "print", "p":
+		fmt.Fprintln(s.out, s.eval(arg))
+	case "watch":
+		s.watches = append(s.watches, arg)
+		fmt.Fprintf(s.out, "%d: %s = %s\n", len(s.watches), arg, s.eval(arg))
+	case "unwatch":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(s.watches) {
+			fmt.Fprintf(s.out, "no watch expression %s\n", arg)
+			break
+		}
+		s.watches = append(s.watches[:n-1], s.watches[n:]...)
+	case "list", "l":
+		start := s.step.Loc().Start
+		s.printLines(start.Filename, start.Line+1, 5)
+	case "help", "h":
+		fmt.Fprint(s.out, debugHelp)
+	default:
+		fmt.Fprintf(s.out, "unknown command %s, help lists the commands\n", name)
+	}
+	return false
+}
+
+// scope returns the innermost scope of the selected frame
+func (s *debugSession) scope() *parse.Scope {
+	return s.step.Frames()[s.frame].Scope
+}
+
+// eval returns the formatted value of the expression in the selected frame or its error
+func (s *debugSession) eval(src string) string {
+	val, err := s.step.Eval(src, s.scope())
+	if err != nil {
+		return "error: " + debug.ErrorMessage(err)
+	}
+	return eval.FormatValue(val)
+}
+
+// setBreakpoint sets or clears the breakpoint at [file:]line, the file of the program by default
+func (s *debugSession) setBreakpoint(arg string, set bool) {
+	file, lineArg := s.main, arg
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, lineArg = arg[:i], arg[i+1:]
+	}
+	line, err := strconv.Atoi(lineArg)
+	if err != nil || line < 1 {
+		fmt.Fprintf(s.out, "invalid breakpoint %q, expected [file:]line\n", arg)
+		return
+	}
+
+	var lines []int
+	for _, l := range s.debugger.Breakpoints(file) {
+		if l != line {
+			lines = append(lines, l)
+		}
+	}
+	if set {
+		lines = append(lines, line)
+	}
+	breakpoints := s.debugger.SetBreakpoints(file, lines)
+	if set {
+		if bp := breakpoints[len(breakpoints)-1]; !bp.Verified {
+			fmt.Fprintf(s.out, "cannot set breakpoint at %s:%d: %s\n", file, line, bp.Message)
+			return
+		}
+		fmt.Fprintf(s.out, "breakpoint at %s:%d\n", file, line)
+	}
+
+	for _, f := range s.files {
+		if s.debugger.Path(f) == s.debugger.Path(file) {
+			return
+		}
+	}
+	s.files = append(s.files, file)
+}
+
+// printLines prints the lines of the file around the line, which is marked
+func (s *debugSession) printLines(file string, line, context int) {
+	lines, ok := s.sources[file]
+	if !ok {
+		for _, f := range s.loader.Files() {
+			s.sources[f.Path] = strings.Split(f.Src, "\n")
+		}
+		lines = s.sources[file]
+	}
+	for i := line - context; i <= line+context; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		marker := "  "
+		if i == line {
+			marker = "=>"
+		}
+		fmt.Fprintf(s.out, "%s %4d  %s\n", marker, i, lines[i-1])
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+// edit is a line of a diff, op is ' ' for unchanged, '-' for removed and '+' for added lines
+type edit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff returns the changes from a to b in unified format with the given file names,
+// it returns an empty string if a and b are equal
+func unifiedDiff(nameA, nameB, a, b string) string {
+	if a == b {
+		return ""
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(edits); {
+		// find the next change and the end of its hunk, changes closer than twice the context share a hunk
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		end := first
+		for unchanged := 0; end < len(edits) && unchanged <= 2*diffContext; end++ {
+			if edits[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > first && edits[end-1].op == ' ' {
+			end--
+		}
+
+		from := max(first-diffContext, start)
+		to := min(end+diffContext, len(edits))
+		writeHunk(&builder, edits, from, to)
+		start = to
+	}
+	return builder.String()
+}
+
+func writeHunk(builder *strings.Builder, edits []edit, from, to int) {
+	// line numbers of the hunk start in both files
+	lineA, lineB := 1, 1
+	for _, e := range edits[:from] {
+		if e.op != '+' {
+			lineA++
+		}
+		if e.op != '-' {
+			lineB++
+		}
+	}
+	countA, countB := 0, 0
+	for _, e := range edits[from:to] {
+		if e.op != '+' {
+			countA++
+		}
+		if e.op != '-' {
+			countB++
+		}
+	}
+
+	fmt.Fprintf(builder, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+	for _, e := range edits[from:to] {
+		builder.WriteByte(e.op)
+		builder.WriteString(e.line)
+		builder.WriteByte('\n')
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes the edits turning a into b from the longest common subsequence of their lines
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := []edit{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
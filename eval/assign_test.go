@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAssignKeepsDeclaredType(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"float f = 1.5\nf = 7\nf / 2", 3.5},
+		{"x := 1.5\nx = 7\nx / 2", 3.5},
+		{"float f = 1.5\nf += 1\nf = f - 0.5\nf", 2.0},
+		{"interface v = 1.5\nv = 7\nv / 2", int64(3)},
+		{"float[] g = [1.5]\ng = [4, 5]\ng[0] = 1.5\ng[0] + g[1]", 6.5},
+		{"a := [1.5]\na = [4]\na[0] = 0.5\na[0]", 0.5},
+		{"fn h(float f) { f = 3\nreturn f / 2 }\nh(1)", 1.5},
+		{"fn k() { float f = 1.5\nf = 7\nx := 0.5\nx = 1\nreturn f / 2 + x / 2 }\nk()", 4.0},
+		{"fn m() { float[] g = [1.5]\ng = [4, 5]\ng[0] = 1.5\nreturn g }\nm()[0]", 1.5},
+	}
+	for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+		for _, test := range tests {
+			interpreter, err := NewInterpreter(Options{Engine: engine})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := interpreter.Eval(context.Background(), test.src)
+			if err != nil || result != test.want {
+				t.Errorf("engine %d: %q: got %v (%T), %v, want %v", engine, test.src, result, result, err, test.want)
+			}
+		}
+	}
+}
+
+func TestAssignChecksDeclaredType(t *testing.T) {
+	for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+		for _, src := range []string{"int n = 1\nn = \"s\"", "fn f() { int n = 1\nn = \"s\" }\nf()", "fn g(int n) { n = \"s\" }\ng(1)"} {
+			interpreter, err := NewInterpreter(Options{Engine: engine})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = interpreter.Eval(context.Background(), src)
+			if err == nil || !strings.Contains(err.Error(), "cannot use string value as int in assignment to n") {
+				t.Errorf("engine %d: %q: got %v, want an assignment error", engine, src, err)
+			}
+		}
+	}
+}
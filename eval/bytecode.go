@@ -0,0 +1,201 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"myProgrammingLanguage/parse"
+)
+
+type Opcode uint8
+
+const (
+	OpConstant         Opcode = iota // push Constants[A]
+	OpNil                            // push nil
+	OpPop                            // discard the top of the stack
+	OpDup                            // duplicate the value on top of the stack
+	OpDup2                           // duplicate the two values on top of the stack
+	OpGetLocal                       // push slot B of the environment A levels up
+	OpSetLocal                       // store the top of the stack in slot B of the environment A levels up
+	OpGetGlobal                      // push the global named Constants[A]
+	OpGetDefinedGlobal               // like OpGetGlobal, for the left side of compound assignments
+	OpGetVar                         // push the variable Constants[A] refers to, B is 1 for compound assignments
+	OpSetVar                         // store the top of the stack in the variable Constants[A] refers to
+	OpSetGlobal                      // assign the global named Constants[A], defining it unless B is 1
+	OpDefineGlobal                   // define the global named Constants[A], B is 1 for functions
+	OpDefineTyped                    // define the global named Constants[A] declared with the type Constants[B]
+	OpDefineType                     // define the global type Constants[B] as Constants[A]
+	OpDeclareType                    // convert the top of the stack to the declared type Constants[A], B is 1 for assignments
+	OpAssignLocal                    // like OpSetLocal for assignments, float variables stay float
+	OpArray                          // replace the top A values with an array of them
+	OpStruct                         // replace the field values on top of the stack with a struct, Constants[A] is its layout
+	OpIndex                          // replace array and index with the element
+	OpCheckIndex                     // check array and index without popping them
+	OpSetIndex                       // store the value on top of the stack in array[index]
+	OpField                          // replace the struct with the value of the field named like the token
+	OpCheckField                     // check the struct has the field named Constants[A] without popping it
+	OpSetField                       // store the value on top of the stack in the field named Constants[A]
+	OpBinary                         // apply the binary operator of the token
+	OpUnary                          // apply the unary operator of the token
+	OpJump                           // continue at A
+	OpJumpIfFalse                    // pop the condition and continue at A if it is false
+	OpPushEnv                        // enter a scope with A slots, B is 1 if they start undeclared
+	OpPopEnv                         // leave the innermost scope
+	OpClosure                        // push a closure of the function Constants[A] in the current environment
+	OpCall                           // call the function below A arguments, Constants[B] describes the callee
+	OpReturn                         // return the top of the stack from the current function
+	OpImport                         // import the module of the import node Constants[A] as a global and push nil
+	OpFail                           // raise the error Constants[A] found while compiling
+)
+
+var opcodeNames = [...]string{
+	OpConstant:         "CONSTANT",
+	OpNil:              "NIL",
+	OpPop:              "POP",
+	OpDup:              "DUP",
+	OpDup2:             "DUP2",
+	OpGetLocal:         "GET_LOCAL",
+	OpSetLocal:         "SET_LOCAL",
+	OpGetGlobal:        "GET_GLOBAL",
+	OpGetDefinedGlobal: "GET_DEFINED_GLOBAL",
+	OpGetVar:           "GET_VAR",
+	OpSetVar:           "SET_VAR",
+	OpSetGlobal:        "SET_GLOBAL",
+	OpDefineGlobal:     "DEFINE_GLOBAL",
+	OpDefineTyped:      "DEFINE_TYPED",
+	OpDefineType:       "DEFINE_TYPE",
+	OpDeclareType:      "DECLARE_TYPE",
+	OpAssignLocal:      "ASSIGN_LOCAL",
+	OpArray:            "ARRAY",
+	OpStruct:           "STRUCT",
+	OpIndex:            "INDEX",
+	OpCheckIndex:       "CHECK_INDEX",
+	OpSetIndex:         "SET_INDEX",
+	OpField:            "FIELD",
+	OpCheckField:       "CHECK_FIELD",
+	OpSetField:         "SET_FIELD",
+	OpBinary:           "BINARY",
+	OpUnary:            "UNARY",
+	OpJump:             "JUMP",
+	OpJumpIfFalse:      "JUMP_IF_FALSE",
+	OpPushEnv:          "PUSH_ENV",
+	OpPopEnv:           "POP_ENV",
+	OpClosure:          "CLOSURE",
+	OpCall:             "CALL",
+	OpReturn:           "RETURN",
+	OpImport:           "IMPORT",
+	OpFail:             "FAIL",
+}
+
+func (op Opcode) String() string {
+	if int(op) < len(opcodeNames) {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("Opcode(%d)", op)
+}
+
+// Instruction is a single vm instruction, the meaning of the operands depends on the opcode
+type Instruction struct {
+	Op   Opcode
+	A, B int32
+}
+
+// Proto is a compiled function or program.
+// Tokens holds the token each instruction is compiled from, it is used for operators and error locations.
+type Proto struct {
+	Name       string
+	Code       []Instruction
+	Tokens     []parse.Token
+	Constants  []interface{}
+	Node       *parse.FunctionNode
+	Params     []*Type
+	ReturnType *Type
+}
+
+func (p *Proto) emit(token parse.Token, op Opcode, a, b int32) int {
+	p.Code = append(p.Code, Instruction{Op: op, A: a, B: b})
+	p.Tokens = append(p.Tokens, token)
+	return len(p.Code) - 1
+}
+
+func (p *Proto) addConstant(val interface{}) int32 {
+	p.Constants = append(p.Constants, val)
+	return int32(len(p.Constants) - 1)
+}
+
+// Disassemble writes the instructions of the proto and of the functions it contains
+func (p *Proto) Disassemble(w io.Writer) {
+	fmt.Fprintf(w, "%s:\n", p.Name)
+	for pc, ins := range p.Code {
+		fmt.Fprintf(w, "  %4d  %-18s %d %d", pc, ins.Op, ins.A, ins.B)
+		if ins.Op == OpConstant || ins.Op == OpGetGlobal || ins.Op == OpSetGlobal || ins.Op == OpDefineGlobal || ins.Op == OpDefineTyped {
+			fmt.Fprintf(w, "\t; %s", formatValue(p.Constants[ins.A]))
+		}
+		fmt.Fprintln(w)
+	}
+	for _, constant := range p.Constants {
+		if proto, ok := constant.(*Proto); ok {
+			fmt.Fprintln(w)
+			proto.Disassemble(w)
+		}
+	}
+}
+
+// structLayout describes a struct literal for OpStruct, the field values are on the stack in the order of fields
+type structLayout struct {
+	typ    *Type
+	fields []parse.Token
+}
+
+// Closure is the runtime value of functions compiled for the vm.
+// Like Function it captures the environment it is created in.
+type Closure struct {
+	proto *Proto
+	env   *env
+	// globals is the global scope of the program the closure is created in, modules have their own
+	globals *parse.Scope
+}
+
+func (c *Closure) Name() string {
+	return c.proto.Name
+}
+
+func (c *Closure) String() string {
+	return c.proto.Node.Signature()
+}
+
+// env holds the slots of the variables of a scope
+type env struct {
+	slots  []interface{}
+	parent *env
+}
+
+// undeclaredSlot is the value of the slots of variables which are not declared yet, only used
+// for environments whose variables functions may refer to before they are declared
+type undeclaredSlot struct{}
+
+// localRef is the slot of a local variable, declared is the type the variable is declared with or nil
+type localRef struct {
+	depth, slot int32
+	declared    *Type
+}
+
+// varRef is a variable a function body refers to which may not be declared yet when the function
+// runs. The variable is the innermost of the locals which is declared or else the global of the name.
+type varRef struct {
+	name   string
+	locals []localRef
+}
+
+// find returns the environment and the slot of the variable, e is nil for the global
+func (ref *varRef) find(frame *env) (e *env, local localRef) {
+	for _, local := range ref.locals {
+		e := frame
+		for depth := local.depth; depth > 0; depth-- {
+			e = e.parent
+		}
+		if _, ok := e.slots[local.slot].(undeclaredSlot); !ok {
+			return e, local
+		}
+	}
+	return nil, localRef{}
+}
@@ -0,0 +1,714 @@
+package eval
+
+import (
+	"myProgrammingLanguage/parse"
+)
+
+// compileScope is a scope known at compile time. Variables of local scopes are resolved to slots,
+// variables of the global scope are looked up by name at runtime, so that they are shared with
+// the host program and later evaluations.
+type compileScope struct {
+	parent *compileScope
+	global bool
+	// hasEnv is false for scopes without variables, they do not create an environment at runtime
+	hasEnv bool
+	// names holds the slots of the variables the statements of the scope declare, slots holds
+	// those declared so far
+	names map[string]int
+	slots map[string]int
+	types map[string]*Type
+	// declared holds the types of the variables declared with one, it is created on first use
+	declared map[string]*Type
+	// pending holds the bodies of functions defined in the scope, they are compiled at the end
+	// of the scope so that they can use variables declared after them
+	pending []func() error
+	// visible is set for the scope of a function body, it holds the number of slots of each
+	// enclosing scope which are declared where the function is defined
+	visible map[*compileScope]int
+	// proto and env locate the OpPushEnv of the scope
+	proto *Proto
+	env   int
+}
+
+// loopState tracks the jumps of break and continue statements of a loop
+type loopState struct {
+	envs      int
+	breaks    []int
+	continues []int
+}
+
+// funcState is the state of the function being compiled
+type funcState struct {
+	proto *Proto
+	// envs is the number of environments pushed since the start of the function
+	envs  int
+	loops []*loopState
+}
+
+// Compiler compiles syntax trees to bytecode for the VM
+type Compiler struct {
+	callStack
+	globals *parse.Scope
+	scope   *compileScope
+	fn      *funcState
+}
+
+// Compile compiles the tree to a proto which can be run by a VM using the same global scope.
+// Errors found while compiling, like unknown types, are raised when the program reaches them
+// like the tree walker reports them.
+func Compile(name string, tree *parse.SyntaxTree, globals *parse.Scope) (*Proto, error) {
+	c := &Compiler{
+		globals: globals,
+		scope:   &compileScope{global: true, types: map[string]*Type{}},
+		fn:      &funcState{proto: &Proto{Name: "<main>"}},
+	}
+
+	// the instructions of an empty program and the final return are at the end of the file
+	token := parse.Token{Loc: parse.TokenLocation{Start: parse.Location{Filename: name}, End: parse.Location{Filename: name}}}
+	if tree != nil && tree.Root != nil {
+		if last := parse.LastToken(tree.Root); last.Loc.Start.Filename != "" {
+			token = last
+		}
+		if err := c.compileStatements(token, tree.Root.(*parse.ProgramNode).Nodes); err != nil {
+			return nil, err
+		}
+	} else {
+		c.emit(token, OpNil, 0, 0)
+	}
+	if err := c.compilePending(); err != nil {
+		return nil, err
+	}
+	c.emit(token, OpReturn, 0, 0)
+
+	c.fn.proto.Name = name
+	return c.fn.proto, nil
+}
+
+func (c *Compiler) emit(token parse.Token, op Opcode, a, b int32) int {
+	return c.fn.proto.emit(token, op, a, b)
+}
+
+func (c *Compiler) constant(val interface{}) int32 {
+	return c.fn.proto.addConstant(val)
+}
+
+// patch makes the jump at pc continue at the next instruction
+func (c *Compiler) patch(pc int) {
+	c.fn.proto.Code[pc].A = int32(len(c.fn.proto.Code))
+}
+
+// fail emits an instruction raising the error found while compiling a node, so that it is raised when
+// the program reaches the node like the tree walker does. Other errors are returned.
+func (c *Compiler) fail(err error) error {
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		return err
+	}
+	c.emit(parse.Token{Loc: runtimeErr.Loc}, OpFail, c.constant(runtimeErr), 0)
+	return nil
+}
+
+// pushScope starts a scope with slots for the variables declared by the given statements
+func (c *Compiler) pushScope(token parse.Token, statements []parse.Node) {
+	count := 0
+	names := map[string]int{}
+	for _, statement := range statements {
+		switch node := statement.(type) {
+		case *parse.VariableDeclarationStatementNode:
+			names[node.Identifier.Val] = count
+			count++
+		case *parse.FunctionNode:
+			if node.Name.Val != "" {
+				names[node.Name.Val] = count
+				count++
+			}
+		}
+	}
+
+	c.scope = &compileScope{parent: c.scope, hasEnv: count > 0, names: names, slots: map[string]int{}, types: map[string]*Type{}}
+	if count > 0 {
+		c.scope.proto, c.scope.env = c.fn.proto, c.emit(token, OpPushEnv, int32(count), 0)
+		c.fn.envs++
+	}
+}
+
+func (c *Compiler) popScope(token parse.Token) error {
+	if err := c.compilePending(); err != nil {
+		return err
+	}
+	if c.scope.hasEnv {
+		c.emit(token, OpPopEnv, 0, 0)
+		c.fn.envs--
+	}
+	c.scope = c.scope.parent
+	return nil
+}
+
+func (c *Compiler) compilePending() error {
+	for len(c.scope.pending) > 0 {
+		body := c.scope.pending[0]
+		c.scope.pending = c.scope.pending[1:]
+		if err := body(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the slot of a local variable, ok is false for globals. A function body may run
+// before the variables of enclosing scopes that are declared after the function, like the tree
+// walker it then uses the variables of outer scopes. For those ref lists the slots the variable
+// may be in and ok is false.
+func (c *Compiler) resolve(name string) (local localRef, ok bool, ref *varRef) {
+	var visible map[*compileScope]int
+	var depth int32
+	for scope := c.scope; !scope.global; scope = scope.parent {
+		index, found := scope.slots[name]
+		if visible != nil {
+			index, found = scope.names[name]
+		}
+		if found {
+			local = localRef{depth: depth, slot: int32(index), declared: scope.declared[name]}
+			if visible == nil || index < visible[scope] {
+				if ref == nil {
+					return local, true, nil
+				}
+				ref.locals = append(ref.locals, local)
+				return localRef{}, false, ref
+			}
+			if ref == nil {
+				ref = &varRef{name: name}
+			}
+			ref.locals = append(ref.locals, local)
+			scope.proto.Code[scope.env].B = 1
+		}
+		if scope.hasEnv {
+			depth++
+		}
+		if scope.visible != nil {
+			visible = scope.visible
+		}
+	}
+	return localRef{}, false, ref
+}
+
+// visibleSlots returns the number of slots declared so far in each enclosing local scope
+func (c *Compiler) visibleSlots() map[*compileScope]int {
+	visible := map[*compileScope]int{}
+	for scope := c.scope; !scope.global; scope = scope.parent {
+		visible[scope] = len(scope.slots)
+		if scope.visible != nil {
+			for outer, n := range scope.visible {
+				visible[outer] = n
+			}
+			break
+		}
+	}
+	return visible
+}
+
+// declareType records the type a local variable of the current scope is declared with
+func (c *Compiler) declareType(name string, typ *Type) {
+	if c.scope.declared == nil {
+		c.scope.declared = map[string]*Type{}
+	}
+	c.scope.declared[name] = typ
+}
+
+// declare defines a variable in the current scope, it returns false for globals
+func (c *Compiler) declare(token parse.Token, what string) (int32, bool, error) {
+	if c.scope.global {
+		return 0, false, nil
+	}
+	if _, ok := c.scope.slots[token.Val]; ok {
+		return 0, false, c.errorAt(token.Loc, "%s %s already defined", what, token.Val)
+	}
+	slot := len(c.scope.slots)
+	c.scope.slots[token.Val] = slot
+	return int32(slot), true, nil
+}
+
+// compileStatements compiles statements leaving the value of the last one on the stack, the value
+// of an empty list is nil at the given token
+func (c *Compiler) compileStatements(token parse.Token, statements []parse.Node) error {
+	if len(statements) == 0 {
+		c.emit(token, OpNil, 0, 0)
+	}
+	for i, statement := range statements {
+		if i > 0 {
+			c.emit(parse.LastToken(statements[i-1]), OpPop, 0, 0)
+		}
+		if err := c.compile(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile compiles a node, every node leaves exactly one value on the stack, the value
+// the tree walker returns for it
+func (c *Compiler) compile(node parse.Node) error {
+	switch node := node.(type) {
+	case *parse.BlockStatementNode:
+		c.pushScope(node.Left, node.Nodes)
+		if err := c.compileStatements(node.Left, node.Nodes); err != nil {
+			return err
+		}
+		return c.popScope(node.Right)
+	case *parse.IfStatementNode:
+		return c.compileIf(node)
+	case *parse.ElseStatementNode:
+		return c.compile(node.Body)
+	case *parse.VariableDeclarationStatementNode:
+		return c.compileVariableDeclaration(node)
+	case *parse.NumberNode:
+		if node.NumberKind == parse.NumberFloat {
+			c.emit(node.Token, OpConstant, c.constant(node.Float), 0)
+		} else {
+			c.emit(node.Token, OpConstant, c.constant(node.Int), 0)
+		}
+	case *parse.BooleanNode:
+		c.emit(node.Token, OpConstant, c.constant(node.Val), 0)
+	case *parse.StringNode:
+		c.emit(node.Token, OpConstant, c.constant(node.Val), 0)
+	case *parse.ArrayLiteralNode:
+		for _, element := range node.Elements {
+			if err := c.compile(element); err != nil {
+				return err
+			}
+		}
+		c.emit(node.LBracket, OpArray, int32(len(node.Elements)), 0)
+	case *parse.IndexExpressionNode:
+		if err := c.compileOperands(node.Left, node.Index); err != nil {
+			return err
+		}
+		c.emit(node.LBracket, OpIndex, 0, 0)
+	case *parse.BinaryExpressionNode:
+		if err := c.compileOperands(node.Left, node.Right); err != nil {
+			return err
+		}
+		c.emit(node.Op, OpBinary, 0, 0)
+	case *parse.ParenthesisedExpressionNode:
+		return c.compile(node.Expression)
+	case *parse.UnaryExpressionNode:
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+		c.emit(node.Op, OpUnary, 0, 0)
+	case *parse.AssignmentExpressionNode:
+		return c.compileAssignment(node)
+	case *parse.CallExpressionNode:
+		if node.IsCall() {
+			return c.compileCall(node)
+		}
+		if local, ok, ref := c.resolve(node.Identifier.Val); ok {
+			c.emit(node.Identifier, OpGetLocal, local.depth, local.slot)
+		} else if ref != nil {
+			c.emit(node.Identifier, OpGetVar, c.constant(ref), 0)
+		} else {
+			c.emit(node.Identifier, OpGetGlobal, c.constant(node.Identifier.Val), 0)
+		}
+	case *parse.FunctionNode:
+		return c.compileFunction(node)
+	case *parse.ReturnStatementNode:
+		if node.Expression == nil {
+			c.emit(node.ReturnToken, OpNil, 0, 0)
+		} else if err := c.compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(node.ReturnToken, OpReturn, 0, 0)
+	case *parse.ForStatementNode:
+		return c.compileFor(node)
+	case *parse.BranchStatementNode:
+		c.compileBranch(node)
+	case *parse.TypeDeclarationNode:
+		return c.compileTypeDeclaration(node)
+	case *parse.StructLiteralNode:
+		return c.compileStructLiteral(node)
+	case *parse.SelectorExpressionNode:
+		if err := c.compile(node.Left); err != nil {
+			return err
+		}
+		c.emit(node.Field, OpField, 0, 0)
+	case *parse.ImportNode:
+		if !c.scope.global {
+			return c.errorAt(node.ImportToken.Loc, "imports must be at the top level")
+		}
+		c.emit(node.ImportToken, OpImport, c.constant(node), 0)
+	default:
+		c.emit(parse.FirstToken(node), OpNil, 0, 0)
+	}
+	return nil
+}
+
+func (c *Compiler) compileOperands(nodes ...parse.Node) error {
+	for _, node := range nodes {
+		if err := c.compile(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileIf(node *parse.IfStatementNode) error {
+	if err := c.compile(node.Expression); err != nil {
+		return err
+	}
+	jumpElse := c.emit(node.IfToken, OpJumpIfFalse, 0, 0)
+	if err := c.compile(node.Body); err != nil {
+		return err
+	}
+	jumpEnd := c.emit(node.IfToken, OpJump, 0, 0)
+
+	c.patch(jumpElse)
+	if node.Else != nil {
+		if err := c.compile(node.Else); err != nil {
+			return err
+		}
+	} else {
+		c.emit(node.IfToken, OpNil, 0, 0)
+	}
+	c.patch(jumpEnd)
+	return nil
+}
+
+func (c *Compiler) compileFor(node *parse.ForStatementNode) error {
+	// variables declared by init statement live in their own scope
+	var init []parse.Node
+	if node.Init != nil {
+		init = append(init, node.Init)
+	}
+	c.pushScope(node.ForToken, init)
+	if node.Init != nil {
+		if err := c.compile(node.Init); err != nil {
+			return err
+		}
+		c.emit(node.ForToken, OpPop, 0, 0)
+	}
+
+	loop := &loopState{envs: c.fn.envs}
+	c.fn.loops = append(c.fn.loops, loop)
+
+	start := len(c.fn.proto.Code)
+	jumpEnd := -1
+	if node.Condition != nil {
+		if err := c.compile(node.Condition); err != nil {
+			return err
+		}
+		jumpEnd = c.emit(node.ForToken, OpJumpIfFalse, 0, 0)
+	}
+
+	if err := c.compile(node.Body); err != nil {
+		return err
+	}
+	c.emit(node.ForToken, OpPop, 0, 0)
+
+	for _, pc := range loop.continues {
+		c.patch(pc)
+	}
+	if node.Post != nil {
+		if err := c.compile(node.Post); err != nil {
+			return err
+		}
+		c.emit(node.ForToken, OpPop, 0, 0)
+	}
+	c.emit(node.ForToken, OpJump, int32(start), 0)
+
+	if jumpEnd >= 0 {
+		c.patch(jumpEnd)
+	}
+	for _, pc := range loop.breaks {
+		c.patch(pc)
+	}
+	c.fn.loops = c.fn.loops[:len(c.fn.loops)-1]
+
+	if err := c.popScope(node.ForToken); err != nil {
+		return err
+	}
+	c.emit(node.ForToken, OpNil, 0, 0)
+	return nil
+}
+
+// compileBranch leaves the environments entered in the loop body and jumps, the parser
+// reports break and continue statements outside of loops
+func (c *Compiler) compileBranch(node *parse.BranchStatementNode) {
+	if len(c.fn.loops) == 0 {
+		c.emit(node.Token, OpNil, 0, 0)
+		return
+	}
+
+	loop := c.fn.loops[len(c.fn.loops)-1]
+	for i := loop.envs; i < c.fn.envs; i++ {
+		c.emit(node.Token, OpPopEnv, 0, 0)
+	}
+	jump := c.emit(node.Token, OpJump, 0, 0)
+	if node.Token.Kind == parse.BREAK {
+		loop.breaks = append(loop.breaks, jump)
+	} else {
+		loop.continues = append(loop.continues, jump)
+	}
+}
+
+func (c *Compiler) compileVariableDeclaration(node *parse.VariableDeclarationStatementNode) error {
+	if node.Expression != nil {
+		if err := c.compile(node.Expression); err != nil {
+			return err
+		}
+	} else {
+		c.emit(node.Identifier, OpNil, 0, 0)
+	}
+
+	slot, local, err := c.declare(node.Identifier, "variable")
+	if err != nil {
+		return c.fail(err)
+	}
+	var typ *Type
+	if node.HasTypeToken {
+		typ, err = resolveType(c, node.Type)
+		if err != nil {
+			return c.fail(err)
+		}
+		c.emit(node.Identifier, OpDeclareType, c.constant(typ), 0)
+	}
+	switch {
+	case local:
+		if typ != nil {
+			c.declareType(node.Identifier.Val, typ)
+		}
+		c.emit(node.Identifier, OpSetLocal, 0, slot)
+	case typ != nil:
+		c.emit(node.Identifier, OpDefineTyped, c.constant(node.Identifier.Val), c.constant(typ))
+	default:
+		c.emit(node.Identifier, OpDefineGlobal, c.constant(node.Identifier.Val), 0)
+	}
+	return nil
+}
+
+func (c *Compiler) compileAssignment(node *parse.AssignmentExpressionNode) error {
+	compound := node.Op.Kind != parse.ASSIGN
+	op := parse.CompoundAssignOperator(node.Op)
+
+	switch target := node.Target.(type) {
+	case *parse.IndexExpressionNode:
+		if err := c.compileOperands(target.Left, target.Index); err != nil {
+			return err
+		}
+		c.emit(target.LBracket, OpCheckIndex, 0, 0)
+		if compound {
+			c.emit(target.LBracket, OpDup2, 0, 0)
+			c.emit(target.LBracket, OpIndex, 0, 0)
+		}
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+		if compound {
+			c.emit(op, OpBinary, 0, 0)
+		}
+		c.emit(node.Op, OpSetIndex, 0, 0)
+		return nil
+	case *parse.SelectorExpressionNode:
+		if err := c.compile(target.Left); err != nil {
+			return err
+		}
+		name := c.constant(target.Field.Val)
+		c.emit(target.Field, OpCheckField, name, 0)
+		if compound {
+			c.emit(target.Field, OpDup, 0, 0)
+			c.emit(target.Field, OpField, 0, 0)
+		}
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+		if compound {
+			c.emit(op, OpBinary, 0, 0)
+		}
+		c.emit(node.Op, OpSetField, name, 0)
+		return nil
+	}
+
+	local, isLocal, ref := c.resolve(node.Identifier.Val)
+	name := int32(-1)
+	switch {
+	case ref != nil:
+		name = c.constant(ref)
+	case !isLocal:
+		name = c.constant(node.Identifier.Val)
+	}
+	if compound {
+		switch {
+		case isLocal:
+			c.emit(node.Identifier, OpGetLocal, local.depth, local.slot)
+		case ref != nil:
+			c.emit(node.Identifier, OpGetVar, name, 1)
+		default:
+			c.emit(node.Identifier, OpGetDefinedGlobal, name, 0)
+		}
+	}
+	if err := c.compile(node.Right); err != nil {
+		return err
+	}
+	if compound {
+		c.emit(op, OpBinary, 0, 0)
+	}
+
+	switch {
+	case isLocal && local.declared != nil:
+		c.emit(node.Identifier, OpDeclareType, c.constant(local.declared), 1)
+		c.emit(node.Identifier, OpSetLocal, local.depth, local.slot)
+	case isLocal:
+		c.emit(node.Identifier, OpAssignLocal, local.depth, local.slot)
+	case ref != nil:
+		c.emit(node.Identifier, OpSetVar, name, 0)
+	case c.scope.global:
+		// for repl, assigning an undefined variable at top level defines it
+		c.emit(node.Identifier, OpSetGlobal, name, 0)
+	default:
+		c.emit(node.Identifier, OpSetGlobal, name, 1)
+	}
+	return nil
+}
+
+func (c *Compiler) compileCall(node *parse.CallExpressionNode) error {
+	if err := c.compile(node.Callee); err != nil {
+		return err
+	}
+	for _, arg := range node.Args {
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+	}
+	c.emit(node.LParen, OpCall, int32(len(node.Args)), c.constant(node.Callee.String()))
+	return nil
+}
+
+// compileFunction resolves the signature and emits the closure, the body is compiled at the end of the scope
+func (c *Compiler) compileFunction(node *parse.FunctionNode) error {
+	proto := &Proto{Name: "<anonymous>", Node: node, Params: make([]*Type, len(node.Params))}
+	if node.Name.Val != "" {
+		proto.Name = node.Name.Val
+	}
+	for i, param := range node.Params {
+		typ, err := resolveType(c, param.Type)
+		if err != nil {
+			return c.fail(err)
+		}
+		proto.Params[i] = typ
+	}
+	if node.ReturnType != nil {
+		typ, err := resolveType(c, node.ReturnType)
+		if err != nil {
+			return c.fail(err)
+		}
+		proto.ReturnType = typ
+	}
+
+	c.emit(node.FnToken, OpClosure, c.constant(proto), 0)
+
+	// named functions are declarations, anonymous ones are just values
+	if node.Name.Val != "" {
+		slot, local, err := c.declare(node.Name, "function")
+		if err != nil {
+			return c.fail(err)
+		}
+		if local {
+			c.emit(node.Name, OpSetLocal, 0, slot)
+		} else {
+			c.emit(node.Name, OpDefineGlobal, c.constant(node.Name.Val), 1)
+		}
+	}
+
+	scope, visible := c.scope, c.visibleSlots()
+	scope.pending = append(scope.pending, func() error {
+		return c.compileBody(proto, scope, visible)
+	})
+	return nil
+}
+
+func (c *Compiler) compileBody(proto *Proto, parent *compileScope, visible map[*compileScope]int) error {
+	fn, scope := c.fn, c.scope
+	defer func() {
+		c.fn, c.scope = fn, scope
+	}()
+
+	// the parameters live in the environment created by the call
+	c.fn = &funcState{proto: proto}
+	c.scope = &compileScope{parent: parent, hasEnv: true, names: map[string]int{}, slots: map[string]int{}, types: map[string]*Type{}, visible: visible}
+	for i, param := range proto.Node.Params {
+		c.scope.names[param.Name.Val] = i
+		c.scope.slots[param.Name.Val] = i
+		c.declareType(param.Name.Val, proto.Params[i])
+	}
+
+	body := proto.Node.Body
+	if err := c.compile(body); err != nil {
+		return err
+	}
+	c.emit(body.Right, OpPop, 0, 0)
+	c.emit(body.Right, OpNil, 0, 0)
+	c.emit(body.Right, OpReturn, 0, 0)
+	return c.compilePending()
+}
+
+func (c *Compiler) compileStructLiteral(node *parse.StructLiteralNode) error {
+	resolved, ok := c.lookupType(node.TypeName.Val)
+	if !ok {
+		return c.fail(c.errorAt(node.TypeName.Loc, "unknown type %s", node.TypeName.Val))
+	}
+	if resolved.Kind != TypeStruct {
+		return c.fail(c.typeErrorAt(node.TypeName.Loc, "%s is not a struct type", node.TypeName.Val))
+	}
+
+	layout := &structLayout{typ: resolved, fields: make([]parse.Token, len(node.Fields))}
+	for i, field := range node.Fields {
+		layout.fields[i] = field.Name
+		if err := c.compile(field.Value); err != nil {
+			return err
+		}
+	}
+	c.emit(node.TypeName, OpStruct, c.constant(layout), 0)
+	return nil
+}
+
+// lookupType finds a declared type in the compile time scopes and the types of the global scope
+func (c *Compiler) lookupType(name string) (*Type, bool) {
+	for scope := c.scope; scope != nil; scope = scope.parent {
+		if typ, ok := scope.types[name]; ok {
+			return typ, true
+		}
+	}
+	if typ, ok := c.globals.ResolveType(name); ok {
+		return typ.(*Type), true
+	}
+	return nil, false
+}
+
+func (c *Compiler) defineType(name string, typ *Type) {
+	c.scope.types[name] = typ
+}
+
+// compileTypeDeclaration defines the type at compile time, global types are defined
+// in the global scope at runtime too
+func (c *Compiler) compileTypeDeclaration(node *parse.TypeDeclarationNode) error {
+	loc := node.Name.Loc
+	if _, ok := builtinTypes[node.Name.Val]; ok {
+		return c.fail(c.errorAt(loc, "cannot redeclare builtin type %s", node.Name.Val))
+	}
+	_, defined := c.scope.types[node.Name.Val]
+	if c.scope.global && !defined {
+		_, defined = c.globals.ResolveLocalType(node.Name.Val)
+	}
+	if defined {
+		return c.fail(c.errorAt(loc, "type %s already defined", node.Name.Val))
+	}
+
+	typ, err := resolveTypeDeclaration(c, node)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if c.scope.global {
+		c.emit(node.Name, OpDefineType, c.constant(typ), c.constant(node.Name.Val))
+	}
+	c.emit(node.Name, OpNil, 0, 0)
+	return nil
+}
@@ -0,0 +1,27 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"myProgrammingLanguage/parse"
+)
+
+// TestLimitErrorsHaveLocations stops programs at every instruction, the errors must point into the file
+func TestLimitErrorsHaveLocations(t *testing.T) {
+	for _, src := range []string{"for { }", "", "x := 1\nif x > 0 { }\nfor i := 0; i < 2; i += 1 { }", "fn f() { }\nf()\n{ }"} {
+		parser := parse.NewParser("loop.pd", src)
+		tree, err := parser.Parse()
+		if err != nil || parser.Errors.HasErrors() {
+			t.Fatalf("%q: parse errors %v %v", src, err, parser.Errors.GetErrors())
+		}
+		for steps := int64(1); steps <= 30; steps++ {
+			_, err := Run(context.Background(), BytecodeVM, "loop.pd", tree, NewGlobalScope(), Limits{MaxSteps: steps})
+			t.Log(steps, err)
+			if err != nil && !strings.HasPrefix(err.Error(), "loop.pd:") {
+				t.Errorf("%q after %d steps: got %v, want an error in loop.pd", src, steps, err)
+			}
+		}
+	}
+}
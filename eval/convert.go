@@ -0,0 +1,225 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// ToValue converts a Go value to a palm runtime value.
+// Integers become int, floats become float and slices become arrays with the element type of the slice.
+// Palm values like arrays, structs and functions are returned as they are.
+func ToValue(val any) (any, error) {
+	switch v := val.(type) {
+	case nil, int64, float64, bool, string, *Array, *Struct, *Function, *NativeFunction, *Closure:
+		return v, nil
+	}
+
+	if rv := reflect.ValueOf(val); rv.Kind() == reflect.Func {
+		return NewNativeFunction("<native>", val)
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows int", rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice, reflect.Array:
+		elem, err := goTypeToType(rv.Type().Elem())
+		if err != nil {
+			return nil, err
+		}
+		arr := &Array{Elem: elem, Elements: make([]interface{}, rv.Len())}
+		for i := range arr.Elements {
+			element, err := ToValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			arr.Elements[i] = element
+		}
+		return arr, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert Go value of type %T to palm value", val)
+}
+
+// goTypeToType returns the palm type of values converted from the given Go type
+func goTypeToType(t reflect.Type) (*Type, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return intType, nil
+	case reflect.Float32, reflect.Float64:
+		return floatType, nil
+	case reflect.Bool:
+		return boolType, nil
+	case reflect.String:
+		return stringType, nil
+	case reflect.Interface:
+		return interfaceType, nil
+	case reflect.Slice, reflect.Array:
+		elem, err := goTypeToType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return arrayOf(elem), nil
+	}
+	return nil, fmt.Errorf("no palm type for Go type %s", t)
+}
+
+// Convert converts a palm runtime value to the Go type T, see FromValue
+func Convert[T any](val any) (T, error) {
+	var result T
+	rv, err := FromValue(val, reflect.TypeOf(&result).Elem())
+	if err != nil {
+		return result, err
+	}
+	result = rv.Interface().(T)
+	return result, nil
+}
+
+// FromValue converts a palm runtime value to a Go value of type t.
+// Arrays are converted to slices and structs to maps with string keys or to Go structs
+// by matching the field names case insensitively. For interface types the natural Go
+// representation is used: int64, float64, bool, string, []any and map[string]any.
+func FromValue(val any, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Interface {
+		natural, err := naturalValue(val)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if natural == nil {
+			return reflect.Zero(t), nil
+		}
+		rv := reflect.ValueOf(natural)
+		if !rv.Type().AssignableTo(t) {
+			return reflect.Value{}, fmt.Errorf("cannot convert %s value to %s", typeOf(val), t)
+		}
+		return rv, nil
+	}
+
+	rv := reflect.New(t).Elem()
+	mismatch := fmt.Errorf("cannot convert %s value to %s", typeOf(val), t)
+
+	switch v := val.(type) {
+	case int64:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if rv.OverflowInt(v) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", v, t)
+			}
+			rv.SetInt(v)
+			return rv, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if v < 0 || rv.OverflowUint(uint64(v)) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", v, t)
+			}
+			rv.SetUint(uint64(v))
+			return rv, nil
+		case reflect.Float32, reflect.Float64:
+			rv.SetFloat(float64(v))
+			return rv, nil
+		}
+	case float64:
+		if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+			rv.SetFloat(v)
+			return rv, nil
+		}
+	case bool:
+		if t.Kind() == reflect.Bool {
+			rv.SetBool(v)
+			return rv, nil
+		}
+	case string:
+		if t.Kind() == reflect.String {
+			rv.SetString(v)
+			return rv, nil
+		}
+	case *Array:
+		if t.Kind() != reflect.Slice {
+			break
+		}
+		rv = reflect.MakeSlice(t, len(v.Elements), len(v.Elements))
+		for i, element := range v.Elements {
+			converted, err := FromValue(element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			rv.Index(i).Set(converted)
+		}
+		return rv, nil
+	case *Struct:
+		return structFromValue(v, t)
+	}
+
+	return reflect.Value{}, mismatch
+}
+
+func structFromValue(s *Struct, t reflect.Type) (reflect.Value, error) {
+	if s == nil {
+		return reflect.Zero(t), nil
+	}
+
+	switch {
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		rv := reflect.MakeMapWithSize(t, len(s.Fields))
+		for i, field := range s.Type.Fields {
+			converted, err := FromValue(s.Fields[i], t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			rv.SetMapIndex(reflect.ValueOf(field.Name).Convert(t.Key()), converted)
+		}
+		return rv, nil
+	case t.Kind() == reflect.Struct:
+		rv := reflect.New(t).Elem()
+		for i, field := range s.Type.Fields {
+			goField, ok := t.FieldByNameFunc(func(name string) bool {
+				return strings.EqualFold(name, field.Name)
+			})
+			if !ok || !goField.IsExported() {
+				continue
+			}
+			converted, err := FromValue(s.Fields[i], goField.Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			rv.FieldByIndex(goField.Index).Set(converted)
+		}
+		return rv, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot convert %s value to %s", s.Type, t)
+}
+
+// naturalValue converts arrays and structs to []any and map[string]any recursively
+func naturalValue(val any) (any, error) {
+	switch v := val.(type) {
+	case *Array:
+		rv, err := FromValue(v, reflect.TypeOf([]any{}))
+		if err != nil {
+			return nil, err
+		}
+		return rv.Interface(), nil
+	case *Struct:
+		if v == nil {
+			return nil, nil
+		}
+		rv, err := FromValue(v, reflect.TypeOf(map[string]any{}))
+		if err != nil {
+			return nil, err
+		}
+		return rv.Interface(), nil
+	}
+	return val, nil
+}
@@ -0,0 +1,103 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"myProgrammingLanguage/parse"
+)
+
+// runEngine runs the program and describes its output, result and error with the stack trace
+func runEngine(t *testing.T, engine Engine, name, src string) string {
+	t.Helper()
+	parser := parse.NewParser(name, src)
+	tree, err := parser.Parse()
+	if err != nil || parser.Errors.HasErrors() {
+		t.Fatalf("%s: parse errors %v %v", name, err, parser.Errors.GetErrors())
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		output <- string(out)
+	}()
+	result, err := Run(context.Background(), engine, name, tree, NewGlobalScope(), Limits{})
+	os.Stdout = stdout
+	w.Close()
+
+	outcome := fmt.Sprintf("output %q result %v", <-output, result)
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		outcome += "\nerror " + err.Error() + "\n" + runtimeErr.StackTrace()
+	} else if err != nil {
+		outcome += "\nerror " + err.Error()
+	}
+	return outcome
+}
+
+func TestEnginesAgreeOnExamples(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "examples", "*.pd"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no examples: %v", err)
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, vm := runEngine(t, TreeWalker, file, string(src)), runEngine(t, BytecodeVM, file, string(src))
+		if tree != vm {
+			t.Errorf("%s:\ntree: %s\nvm:   %s", file, tree, vm)
+		}
+	}
+}
+
+func TestEnginesAgreeOnScoping(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// a function sees the variables of enclosing scopes declared before it is called
+		{"x := 1\nr := [0, 0]\nif true {\nfn f() int { return x }\nr[0] = f()\nx := 5\nr[1] = f()\n}\nr", `output "" result [1, 5]`},
+		{"if true {\nfn g() { return y }\ng()\ny := 2\n}", ""},
+		{"fn outer() bool {\nfn even(int n) bool {\nif n == 0 { return true }\nreturn odd(n - 1)\n}\nfn odd(int n) bool {\nif n == 0 { return false }\nreturn even(n - 1)\n}\nreturn even(10)\n}\nouter()", `output "" result true`},
+		{"fn outer() int {\nn := 1\nif true {\nfn f() int { return n }\nfn g() int { return f() }\nn := 2\nreturn g()\n}\n}\nouter()", `output "" result 2`},
+		{"x := 1\nr := [0, 0]\nif true {\nfn set() { x = 3 }\nset()\nr[0] = x\nx := 5\nset()\nr[1] = x\n}\n[r[0], r[1], x]", `output "" result [3, 3, 3]`},
+		{"r := [0, 0, 0]\nfor i := 0; i < 3; i += 1 {\nfn f() int { return v }\nv := i\nr[i] = f()\n}\nr", `output "" result [0, 1, 2]`},
+		{"x := 1.5\nif true {\nfn f() { x = 2 }\nfloat y = 0\nf()\n}\nx", `output "" result 2`},
+		// assigning an undefined variable defines it only at top level
+		{"found = 5\nfound", `output "" result 5`},
+		{"if true { found = 5 }\nfound", "output \"\" result <nil>\nerror scoping.pd:1:11: error: variable found not defined\n\tat <main> (scoping.pd:1:11)\n"},
+		{"fn f() { g = 5 }\nf()\ng", ""},
+		{"fn f() { g = 5 }\ng := 1\nf()\ng", `output "" result 5`},
+		// errors like unknown types are raised when the program reaches them
+		{"println(1)\nunknown x = 1", ""},
+		{"println(2)\np := Q{}", ""},
+		{"println(3)\nfn f(unknown u) { }", ""},
+		{"fn f() { type T = int\ntype T = int }\nprintln(4)\nf()", ""},
+		{"fn never() { x := 1\nx := 2 }\n5", `output "" result 5`},
+		// function literals in blocks and function bodies take no slot, only their variables do
+		{"if true { j := fn() int { return 1 }\nprintln(j()) }", `output "1\n" result <nil>`},
+		{"fn f() int { j := fn() int { return 2 }\nreturn j() }\nf()", `output "" result 2`},
+	}
+	for _, test := range tests {
+		tree, vm := runEngine(t, TreeWalker, "scoping.pd", test.src), runEngine(t, BytecodeVM, "scoping.pd", test.src)
+		if tree != vm {
+			t.Errorf("%q:\ntree: %s\nvm:   %s", test.src, tree, vm)
+		}
+		if test.want != "" && tree != test.want {
+			t.Errorf("%q: got %s, want %s", test.src, tree, test.want)
+		}
+	}
+}
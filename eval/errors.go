@@ -0,0 +1,171 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"myProgrammingLanguage/parse"
+	"strings"
+)
+
+// Frame is an entry of the call stack of a RuntimeError.
+// Loc is the position of the execution in the function, the failing node for the innermost frame
+// and the call of the next frame for the others.
+type Frame struct {
+	Function string
+	Loc      parse.TokenLocation
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, f.Loc.Start.Filename, f.Loc.Start.Line+1, f.Loc.Start.Col+1)
+}
+
+// RuntimeErrorKind classifies runtime errors, a kind matches a RuntimeError of that kind with errors.Is
+type RuntimeErrorKind int
+
+const (
+	GenericError RuntimeErrorKind = iota
+	// TypeError is raised for values of unexpected types like operands of operators
+	TypeError
+	// DivisionByZero is raised for integer division and remainder by zero
+	DivisionByZero
+	// InternalError is a Go panic recovered by Evaluate
+	InternalError
+	// LimitExceeded is raised when the code exceeds the Limits of the engine or its context is done
+	LimitExceeded
+	// AssertionFailed is raised by the assert and assertEq builtins
+	AssertionFailed
+)
+
+var runtimeErrorKindNames = map[RuntimeErrorKind]string{
+	GenericError:    "runtime error",
+	TypeError:       "type error",
+	DivisionByZero:  "division by zero",
+	InternalError:   "internal error",
+	LimitExceeded:   "limit exceeded",
+	AssertionFailed: "assertion failed",
+}
+
+func (k RuntimeErrorKind) String() string {
+	return runtimeErrorKindNames[k]
+}
+
+func (k RuntimeErrorKind) Error() string {
+	return k.String()
+}
+
+// RuntimeError is an error raised while evaluating palm code
+type RuntimeError struct {
+	Kind  RuntimeErrorKind
+	Loc   parse.TokenLocation
+	Msg   string
+	Stack []Frame
+	// Err is the wrapped cause like the error returned by a native function
+	Err error
+}
+
+// Error formats the error like parse.Err, as file:line:col: error: msg
+func (r *RuntimeError) Error() string {
+	return r.Diagnostic().String()
+}
+
+// Diagnostic returns the error as a diagnostic for parse.Renderer
+func (r *RuntimeError) Diagnostic() parse.Err {
+	return parse.Err{
+		File: r.Loc.Start.Filename,
+		Len:  r.Loc.End.Offset - r.Loc.Start.Offset,
+		Loc:  r.Loc,
+		Msg:  r.Msg,
+		Kind: parse.Error,
+	}
+}
+
+func (r *RuntimeError) Unwrap() error {
+	return r.Err
+}
+
+// Is reports whether target is the kind of the error
+func (r *RuntimeError) Is(target error) bool {
+	kind, ok := target.(RuntimeErrorKind)
+	return ok && kind == r.Kind
+}
+
+// stackTraceEnds is the number of innermost and outermost frames shown of deep stacks
+const stackTraceEnds = 10
+
+// StackTrace returns the call stack starting from the innermost frame, one frame per line. The frames
+// between the innermost and the outermost ones of deep stacks are elided.
+func (r *RuntimeError) StackTrace() string {
+	builder := strings.Builder{}
+	for i, frame := range r.Stack {
+		if elided := len(r.Stack) - 2*stackTraceEnds; elided > 1 && i >= stackTraceEnds && i < stackTraceEnds+elided {
+			if i == stackTraceEnds {
+				fmt.Fprintf(&builder, "\t... %d frames elided\n", elided)
+			}
+			continue
+		}
+		builder.WriteString("\tat ")
+		builder.WriteString(frame.String())
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// call is an active function call of the evaluator, the tree walker keeps the scope of the caller and
+// the scope of the parameters in it for debuggers
+type call struct {
+	function string
+	loc      parse.TokenLocation
+	scope    *parse.Scope
+	locals   *parse.Scope
+}
+
+// callStack tracks the active calls of an engine, operations which can fail are methods of it
+// so that the tree walker and the vm report errors the same way.
+type callStack struct {
+	calls  []call
+	limits Limits
+	steps  int64
+}
+
+// errorAt creates a RuntimeError at loc with the current call stack.
+// The format is handled by fmt.Errorf, so that %w wraps the cause.
+func (s *callStack) errorAt(loc parse.TokenLocation, format string, args ...any) error {
+	return s.newError(GenericError, loc, format, args...)
+}
+
+// typeErrorAt creates a RuntimeError of kind TypeError, see errorAt
+func (s *callStack) typeErrorAt(loc parse.TokenLocation, format string, args ...any) error {
+	return s.newError(TypeError, loc, format, args...)
+}
+
+func (s *callStack) newError(kind RuntimeErrorKind, loc parse.TokenLocation, format string, args ...any) error {
+	cause := fmt.Errorf(format, args...)
+	return &RuntimeError{
+		Kind:  kind,
+		Loc:   loc,
+		Msg:   cause.Error(),
+		Stack: s.stackTrace(loc),
+		Err:   errors.Unwrap(cause),
+	}
+}
+
+func (s *callStack) stackTrace(loc parse.TokenLocation) []Frame {
+	frames := make([]Frame, 0, len(s.calls)+1)
+	for i := len(s.calls) - 1; i >= 0; i-- {
+		frames = append(frames, Frame{Function: s.calls[i].function, Loc: loc})
+		loc = s.calls[i].loc
+	}
+	return append(frames, Frame{Function: "<main>", Loc: loc})
+}
+
+// recovered converts a recovered Go panic to an InternalError reported at the call of the innermost function
+func (s *callStack) recovered(r any) error {
+	var loc parse.TokenLocation
+	if len(s.calls) > 0 {
+		loc = s.calls[len(s.calls)-1].loc
+		s.calls = s.calls[:len(s.calls)-1]
+	}
+	err := s.newError(InternalError, loc, "internal error: %v", r)
+	s.calls = nil
+	return err
+}
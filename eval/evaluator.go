@@ -0,0 +1,935 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+)
+
+type Evaluator struct {
+	tree  *parse.SyntaxTree
+	scope *parse.Scope
+	// globals is the top level scope of the program
+	globals *parse.Scope
+	ctx     context.Context
+	imports *Importer
+	hook    Hook
+	// baseDepth is the number of calls active when the evaluation started, like the ones importing a module
+	baseDepth int
+	callStack
+}
+
+func (e *Evaluator) popScope() {
+	e.scope = e.scope.Parent()
+}
+
+func (e *Evaluator) pushScope() {
+	e.scope = parse.NewScope(e.scope)
+}
+
+// peekScope looks up the scope chain for the given parentStep if it is not fount returns top level
+func (e *Evaluator) peekScope(parentStep int) *parse.Scope {
+	scope := e.scope
+	for i := 0; i < parentStep; i++ {
+		if scope.Parent() == nil {
+			scope = scope.Parent()
+		} else {
+			break
+		}
+	}
+	return scope
+}
+
+func NewEvaluator(tree *parse.SyntaxTree, scope *parse.Scope) *Evaluator {
+	e := Evaluator{tree: tree, scope: scope, globals: scope, ctx: context.Background()}
+	return &e
+}
+
+// SetLimits bounds the work of Evaluate, see Limits
+func (e *Evaluator) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// Evaluate runs the tree. Go panics raised while evaluating are recovered and returned
+// as InternalError, so no input can crash the host program.
+func (e *Evaluator) Evaluate() (result interface{}, err error) {
+	if e.tree == nil || e.tree.Root == nil {
+		return nil, nil
+	}
+
+	scope := e.scope
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, e.recovered(r)
+			e.scope = scope
+		}
+	}()
+
+	val, err := e.visitNode(e.tree.Root)
+	// top level return statement ends the program with its value
+	if ret, ok := err.(*returnSignal); ok {
+		return ret.value, nil
+	}
+	if signal, ok := err.(*loopSignal); ok {
+		return nil, e.errorAt(signal.token.Loc, "%s is not in a loop", signal.token.Val)
+	}
+	return val, err
+}
+
+// returnSignal unwinds the evaluation up to the enclosing function call
+type returnSignal struct {
+	value interface{}
+}
+
+func (r *returnSignal) Error() string {
+	return "return outside of function"
+}
+
+// loopSignal unwinds the evaluation up to the enclosing loop on break and continue
+type loopSignal struct {
+	token parse.Token
+}
+
+func (l *loopSignal) Error() string {
+	return l.token.Val + " is not in a loop"
+}
+
+func (e *Evaluator) visitNode(node parse.Node) (interface{}, error) {
+	if err := e.step(e.ctx, func() parse.TokenLocation { return parse.FirstToken(node).Loc }); err != nil {
+		return nil, err
+	}
+	if e.hook != nil {
+		if err := e.hook(&Step{Node: node, Scope: e.scope, Depth: e.baseDepth + len(e.calls), e: e}); err != nil {
+			return nil, err
+		}
+	}
+
+	switch node.Kind() {
+	case parse.NodeProgram:
+		return e.visitProgramNode(node.(*parse.ProgramNode))
+	case parse.NodeBlockStatement:
+		return e.visitBlockStatementNode(node.(*parse.BlockStatementNode))
+	case parse.NodeIfStatement:
+		return e.visitIfStatementNode(node.(*parse.IfStatementNode))
+	case parse.NodeElseStatement:
+		return e.visitElseStatementNode(node.(*parse.ElseStatementNode))
+	case parse.NodeVariableDeclaration:
+		return e.visitVariableDeclarationNode(node.(*parse.VariableDeclarationStatementNode))
+	case parse.NodeNumber:
+		return e.visitNumberNode(node.(*parse.NumberNode)), nil
+	case parse.NodeBoolean:
+		return e.visitBooleanNode(node.(*parse.BooleanNode)), nil
+	case parse.NodeString:
+		return e.visitStringNode(node.(*parse.StringNode)), nil
+	case parse.NodeArrayLiteral:
+		return e.visitArrayLiteralNode(node.(*parse.ArrayLiteralNode))
+	case parse.NodeIndexExpression:
+		return e.visitIndexExpressionNode(node.(*parse.IndexExpressionNode))
+	case parse.NodeBinaryExpression:
+		return e.visitBinaryExpressionNode(node.(*parse.BinaryExpressionNode))
+	case parse.NodeParenthesisedExpression:
+		return e.visitNode(node.(*parse.ParenthesisedExpressionNode).Expression)
+	case parse.NodeUnaryExpression:
+		return e.visitUnaryExpressionNode(node.(*parse.UnaryExpressionNode))
+	case parse.NodeAssignmentExpression:
+		return e.visitAssignmentExpressionNode(node.(*parse.AssignmentExpressionNode))
+	case parse.NodeCallExpression:
+		if call := node.(*parse.CallExpressionNode); call.IsCall() {
+			return e.visitCallExpressionNode(call)
+		}
+		return e.visitIdentifierAccessExpressionNode(node.(*parse.CallExpressionNode))
+	case parse.NodeFunction:
+		return e.visitFunctionNode(node.(*parse.FunctionNode))
+	case parse.NodeReturnStatement:
+		return e.visitReturnStatementNode(node.(*parse.ReturnStatementNode))
+	case parse.NodeForStatement:
+		return e.visitForStatementNode(node.(*parse.ForStatementNode))
+	case parse.NodeBranchStatement:
+		return nil, &loopSignal{token: node.(*parse.BranchStatementNode).Token}
+	case parse.NodeTypeDeclaration:
+		return e.visitTypeDeclarationNode(node.(*parse.TypeDeclarationNode))
+	case parse.NodeStructLiteral:
+		return e.visitStructLiteralNode(node.(*parse.StructLiteralNode))
+	case parse.NodeSelectorExpression:
+		return e.visitSelectorExpressionNode(node.(*parse.SelectorExpressionNode))
+	case parse.NodeImport:
+		return e.visitImportNode(node.(*parse.ImportNode))
+	}
+	return nil, nil
+}
+
+func (e *Evaluator) visitNumberNode(node *parse.NumberNode) interface{} {
+	if node.NumberKind == parse.NumberFloat {
+		return node.Float
+	}
+	return node.Int
+}
+
+func (e *Evaluator) visitBooleanNode(node *parse.BooleanNode) bool {
+	return node.Val
+}
+
+func (e *Evaluator) visitStringNode(node *parse.StringNode) string {
+	return node.Val
+}
+
+func (e *Evaluator) visitArrayLiteralNode(node *parse.ArrayLiteralNode) (interface{}, error) {
+	elements := make([]interface{}, len(node.Elements))
+	for i, element := range node.Elements {
+		val, err := e.visitNode(element)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = val
+	}
+
+	arr := newArray(elements)
+	if err := e.checkValueSize(node.LBracket.Loc, arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+func (e *Evaluator) visitIndexExpressionNode(node *parse.IndexExpressionNode) (interface{}, error) {
+	arr, index, err := e.evalIndex(node)
+	if err != nil {
+		return nil, err
+	}
+	return arr.Elements[index], nil
+}
+
+// evalIndex evaluates the array and the index of an index expression and checks the bounds
+func (e *Evaluator) evalIndex(node *parse.IndexExpressionNode) (*Array, int, error) {
+	left, err := e.visitNode(node.Left)
+	if err != nil {
+		return nil, 0, err
+	}
+	index, err := e.visitNode(node.Index)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return e.indexArray(node.LBracket.Loc, left, index)
+}
+
+// indexArray checks that left is an array and index is in its bounds
+func (s *callStack) indexArray(loc parse.TokenLocation, left, index interface{}) (*Array, int, error) {
+	arr, ok := left.(*Array)
+	if !ok {
+		return nil, 0, s.typeErrorAt(loc, "cannot index value of type %s", typeOf(left))
+	}
+	i, ok := index.(int64)
+	if !ok {
+		return nil, 0, s.typeErrorAt(loc, "array index must be int, got %s", typeOf(index))
+	}
+	if i < 0 || i >= int64(len(arr.Elements)) {
+		return nil, 0, s.errorAt(loc, "index out of range [%d] with length %d", i, len(arr.Elements))
+	}
+	return arr, int(i), nil
+}
+
+func (e *Evaluator) visitStructLiteralNode(node *parse.StructLiteralNode) (interface{}, error) {
+	loc := node.TypeName.Loc
+	resolved, ok := e.scope.ResolveType(node.TypeName.Val)
+	if !ok {
+		return nil, e.errorAt(loc, "unknown type %s", node.TypeName.Val)
+	}
+	typ := resolved.(*Type)
+	if typ.Kind != TypeStruct {
+		return nil, e.typeErrorAt(loc, "%s is not a struct type", node.TypeName.Val)
+	}
+
+	s := zeroValue(typ).(*Struct)
+	for _, field := range node.Fields {
+		fieldLoc := field.Name.Loc
+		index := typ.FieldIndex(field.Name.Val)
+		if index < 0 {
+			return nil, e.errorAt(fieldLoc, "unknown field %s in struct literal of type %s", field.Name.Val, typ)
+		}
+
+		val, err := e.visitNode(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		converted, ok := convertTo(typ.Fields[index].Type, val)
+		if !ok {
+			return nil, e.typeErrorAt(fieldLoc, "cannot use %s value as %s in field %s", typeOf(val), typ.Fields[index].Type, field.Name.Val)
+		}
+		s.Fields[index] = converted
+	}
+
+	return s, nil
+}
+
+func (e *Evaluator) visitSelectorExpressionNode(node *parse.SelectorExpressionNode) (interface{}, error) {
+	left, err := e.visitNode(node.Left)
+	if err != nil {
+		return nil, err
+	}
+	if mod, ok := left.(*Module); ok {
+		return e.moduleMember(node.Field, mod)
+	}
+
+	s, index, err := e.structField(node.Field, left)
+	if err != nil {
+		return nil, err
+	}
+	return s.Fields[index], nil
+}
+
+// visitImportNode evaluates the imported module, evaluators created by NewEvaluator import with the
+// tree walker and the default search path
+func (e *Evaluator) visitImportNode(node *parse.ImportNode) (interface{}, error) {
+	if e.imports == nil {
+		e.imports = NewImporter(module.NewLoader(module.DefaultSearchPath()), TreeWalker, e.limits)
+	}
+	return nil, e.imports.importModule(e.ctx, &e.callStack, node, e.scope)
+}
+
+// evalSelector evaluates the struct of a selector expression and looks up the field
+func (e *Evaluator) evalSelector(node *parse.SelectorExpressionNode) (*Struct, int, error) {
+	left, err := e.visitNode(node.Left)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return e.structField(node.Field, left)
+}
+
+// structField checks that left is a struct and looks up the index of the field
+func (s *callStack) structField(field parse.Token, left interface{}) (*Struct, int, error) {
+	// members of modules are read by moduleMember, so this is an assignment
+	if mod, ok := left.(*Module); ok {
+		return nil, 0, s.errorAt(field.Loc, "cannot assign to %s.%s, module members are read only", mod.Name, field.Val)
+	}
+	st, ok := left.(*Struct)
+	if !ok || st == nil {
+		return nil, 0, s.typeErrorAt(field.Loc, "cannot access field %s of %s value", field.Val, typeOf(left))
+	}
+	index := st.Type.FieldIndex(field.Val)
+	if index < 0 {
+		return nil, 0, s.errorAt(field.Loc, "type %s has no field %s", st.Type, field.Val)
+	}
+	return st, index, nil
+}
+
+func (e *Evaluator) visitBinaryExpressionNode(node *parse.BinaryExpressionNode) (interface{}, error) {
+
+	left, err := e.visitNode(node.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.visitNode(node.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.applyBinaryOperator(node.Op, left, right)
+}
+
+// applyBinaryOperator evaluates op on already evaluated operands.
+// If any of the operands is a float, both of them are promoted to float.
+func (s *callStack) applyBinaryOperator(op parse.Token, left, right interface{}) (interface{}, error) {
+	_, leftIsString := left.(string)
+	_, rightIsString := right.(string)
+	if leftIsString || rightIsString {
+		return s.applyStringOperator(op, left, right)
+	}
+
+	_, leftIsFloat := left.(float64)
+	_, rightIsFloat := right.(float64)
+	if leftIsFloat || rightIsFloat {
+		return s.applyFloatOperator(op, left, right)
+	}
+
+	if op.Kind == parse.EQ || op.Kind == parse.NEQ {
+		if !typeOf(left).Equal(typeOf(right)) {
+			return nil, s.typeErrorAt(op.Loc, "mismatched types %s and %s for operator %s", typeOf(left), typeOf(right), op.Val)
+		}
+		return (left == right) == (op.Kind == parse.EQ), nil
+	}
+
+	leftBool, leftIsBool := left.(bool)
+	rightBool, rightIsBool := right.(bool)
+	if leftIsBool && rightIsBool {
+		switch op.Kind {
+		case parse.AND:
+			return leftBool && rightBool, nil
+		case parse.OR:
+			return leftBool || rightBool, nil
+		}
+		return nil, s.typeErrorAt(op.Loc, "operator %s is not defined on bool", op.Val)
+	}
+
+	l, leftIsInt := left.(int64)
+	r, rightIsInt := right.(int64)
+	if !leftIsInt || !rightIsInt {
+		if leftIsInt || rightIsInt || typeOf(left).Equal(typeOf(right)) {
+			return nil, s.typeErrorAt(op.Loc, "mismatched types %s and %s for operator %s", typeOf(left), typeOf(right), op.Val)
+		}
+		return nil, s.typeErrorAt(op.Loc, "operator %s is not defined on %s", op.Val, typeOf(left))
+	}
+
+	switch op.Kind {
+	case parse.PLUS:
+		return l + r, nil
+	case parse.MINUS:
+		return l - r, nil
+	case parse.MUL:
+		return l * r, nil
+	case parse.QUO, parse.REM:
+		if r == 0 {
+			return nil, s.newError(DivisionByZero, op.Loc, "integer division by zero")
+		}
+		if op.Kind == parse.QUO {
+			return l / r, nil
+		}
+		return l % r, nil
+	case parse.AND, parse.BITAND:
+		return l & r, nil
+	case parse.OR, parse.BITOR:
+		return l | r, nil
+	case parse.LT:
+		return l < r, nil
+	case parse.LTE:
+		return l <= r, nil
+	case parse.GT:
+		return l > r, nil
+	case parse.GTE:
+		return l >= r, nil
+	case parse.XOR:
+		return l ^ r, nil
+	case parse.LSHIFT, parse.RSHIFT:
+		if r < 0 {
+			return nil, s.errorAt(op.Loc, "negative shift amount %d", r)
+		}
+		if op.Kind == parse.LSHIFT {
+			return l << r, nil
+		}
+		return l >> r, nil
+	}
+
+	return nil, s.typeErrorAt(op.Loc, "operator %s is not defined on int", op.Val)
+}
+
+func (s *callStack) applyFloatOperator(op parse.Token, left, right interface{}) (interface{}, error) {
+	l, ok := toFloat(left)
+	if !ok {
+		return nil, s.typeErrorAt(op.Loc, "mismatched types %s and %s for operator %s", typeOf(left), typeOf(right), op.Val)
+	}
+	r, ok := toFloat(right)
+	if !ok {
+		return nil, s.typeErrorAt(op.Loc, "mismatched types %s and %s for operator %s", typeOf(left), typeOf(right), op.Val)
+	}
+
+	switch op.Kind {
+	case parse.PLUS:
+		return l + r, nil
+	case parse.MINUS:
+		return l - r, nil
+	case parse.MUL:
+		return l * r, nil
+	case parse.QUO:
+		return l / r, nil
+	case parse.REM:
+		return math.Mod(l, r), nil
+	case parse.EQ:
+		return l == r, nil
+	case parse.NEQ:
+		return l != r, nil
+	case parse.LT:
+		return l < r, nil
+	case parse.LTE:
+		return l <= r, nil
+	case parse.GT:
+		return l > r, nil
+	case parse.GTE:
+		return l >= r, nil
+	}
+
+	return nil, s.typeErrorAt(op.Loc, "operator %s is not defined on float", op.Val)
+}
+
+func (s *callStack) applyStringOperator(op parse.Token, left, right interface{}) (interface{}, error) {
+	l, leftOk := left.(string)
+	r, rightOk := right.(string)
+	if !leftOk || !rightOk {
+		return nil, s.typeErrorAt(op.Loc, "mismatched types %s and %s for operator %s", typeOf(left), typeOf(right), op.Val)
+	}
+
+	switch op.Kind {
+	case parse.PLUS:
+		// the size is checked before concatenating, so that the string is never allocated
+		if err := s.checkSize(op.Loc, len(l)+len(r), "string of %d bytes"); err != nil {
+			return nil, err
+		}
+		return l + r, nil
+	case parse.EQ:
+		return l == r, nil
+	case parse.NEQ:
+		return l != r, nil
+	case parse.LT:
+		return l < r, nil
+	case parse.LTE:
+		return l <= r, nil
+	case parse.GT:
+		return l > r, nil
+	case parse.GTE:
+		return l >= r, nil
+	}
+
+	return nil, s.typeErrorAt(op.Loc, "operator %s is not defined on string", op.Val)
+}
+
+// toFloat promotes int and float values to float64
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func (e *Evaluator) visitUnaryExpressionNode(node *parse.UnaryExpressionNode) (interface{}, error) {
+	right, err := e.visitNode(node.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.applyUnaryOperator(node.Op, right)
+}
+
+func (s *callStack) applyUnaryOperator(op parse.Token, right interface{}) (interface{}, error) {
+	switch val := right.(type) {
+	case int64:
+		switch op.Kind {
+		case parse.PLUS:
+			return val, nil
+		case parse.MINUS:
+			return -val, nil
+		}
+	case float64:
+		switch op.Kind {
+		case parse.PLUS:
+			return val, nil
+		case parse.MINUS:
+			return -val, nil
+		}
+	case bool:
+		if op.Kind == parse.NOT {
+			return !val, nil
+		}
+	}
+
+	return nil, s.typeErrorAt(op.Loc, "operator %s is not defined on %s", op.Val, typeOf(right))
+}
+
+// assignVariable assigns the variable in the nearest scope that defines it, converting the value
+// with assignedValue. An undefined variable is defined in the scope if define is set, that is at
+// the top level of a program, elsewhere it is an error. It returns the assigned value.
+func (s *callStack) assignVariable(scope *parse.Scope, name parse.Token, val interface{}, define bool) (interface{}, error) {
+	if assignsBuiltin(scope, name.Val) {
+		return nil, s.errorAt(name.Loc, "cannot assign to builtin %s", name.Val)
+	}
+	current, ok := scope.Resolve(name.Val)
+	if !ok {
+		if !define {
+			return nil, s.errorAt(name.Loc, "variable %s not defined", name.Val)
+		}
+		scope.Define(name.Val, val)
+		return val, nil
+	}
+
+	var declared *Type
+	if typ, ok := scope.DeclaredType(name.Val); ok {
+		declared = typ.(*Type)
+	}
+	converted, ok := assignedValue(declared, current, val)
+	if !ok {
+		return nil, s.typeErrorAt(name.Loc, "cannot use %s value as %s in assignment to %s", typeOf(val), declared, name.Val)
+	}
+	scope.Assign(name.Val, converted)
+	return converted, nil
+}
+
+func (e *Evaluator) visitAssignmentExpressionNode(node *parse.AssignmentExpressionNode) (interface{}, error) {
+	switch target := node.Target.(type) {
+	case *parse.IndexExpressionNode:
+		return e.visitIndexAssignment(node, target)
+	case *parse.SelectorExpressionNode:
+		return e.visitSelectorAssignment(node, target)
+	}
+
+	resolvedVal, ok := e.scope.Resolve(node.Identifier.Val)
+
+	switch node.Op.Kind {
+	case parse.ASSIGN:
+		val, err := e.visitNode(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		// for repl, assigning an undefined variable at top level defines it
+		return e.assignVariable(e.scope, node.Identifier, val, e.scope == e.globals)
+	case parse.PLUS_ASSIGN, parse.MINUS_ASSIGN, parse.MUL_ASSIGN, parse.QUO_ASSIGN, parse.REM_ASSIGN:
+		if !ok {
+			return nil, e.errorAt(node.Identifier.Loc, "variable %s not defined", node.Identifier.Val)
+		}
+		val, err := e.visitNode(node.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := e.applyBinaryOperator(parse.CompoundAssignOperator(node.Op), resolvedVal, val)
+		if err != nil {
+			return nil, err
+		}
+
+		return e.assignVariable(e.scope, node.Identifier, result, false)
+
+	}
+	return nil, nil
+}
+
+func (e *Evaluator) visitIndexAssignment(node *parse.AssignmentExpressionNode, target *parse.IndexExpressionNode) (interface{}, error) {
+	arr, index, err := e.evalIndex(target)
+	if err != nil {
+		return nil, err
+	}
+	val, err := e.visitNode(node.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Op.Kind != parse.ASSIGN {
+		val, err = e.applyBinaryOperator(parse.CompoundAssignOperator(node.Op), arr.Elements[index], val)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	converted, ok := convertTo(arr.Elem, val)
+	if !ok {
+		loc := node.Op.Loc
+		return nil, e.typeErrorAt(loc, "cannot use %s value as %s in array assignment", typeOf(val), arr.Elem)
+	}
+
+	arr.Elements[index] = converted
+	return converted, nil
+}
+
+func (e *Evaluator) visitSelectorAssignment(node *parse.AssignmentExpressionNode, target *parse.SelectorExpressionNode) (interface{}, error) {
+	s, index, err := e.evalSelector(target)
+	if err != nil {
+		return nil, err
+	}
+	val, err := e.visitNode(node.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Op.Kind != parse.ASSIGN {
+		val, err = e.applyBinaryOperator(parse.CompoundAssignOperator(node.Op), s.Fields[index], val)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	field := s.Type.Fields[index]
+	converted, ok := convertTo(field.Type, val)
+	if !ok {
+		loc := node.Op.Loc
+		return nil, e.typeErrorAt(loc, "cannot use %s value as %s in assignment to field %s", typeOf(val), field.Type, field.Name)
+	}
+
+	s.Fields[index] = converted
+	return converted, nil
+}
+
+func (e *Evaluator) visitIdentifierAccessExpressionNode(node *parse.CallExpressionNode) (interface{}, error) {
+	val, ok := e.scope.Resolve(node.Identifier.Val)
+	if !ok {
+		return nil, e.errorAt(node.Identifier.Loc, "undefined variable %s", node.Identifier.Val)
+	}
+
+	return val, nil
+}
+
+func (e *Evaluator) visitCallExpressionNode(node *parse.CallExpressionNode) (interface{}, error) {
+	callee, err := e.visitNode(node.Callee)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := node.LParen.Loc
+	native, isNative := callee.(*NativeFunction)
+	fn, ok := callee.(*Function)
+	if !ok && !isNative {
+		return nil, e.typeErrorAt(loc, "cannot call non-function %s of type %s", node.Callee, typeOf(callee))
+	}
+
+	args := make([]interface{}, len(node.Args))
+	for i, arg := range node.Args {
+		args[i], err = e.visitNode(arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isNative {
+		return e.callNative(e.ctx, loc, native, args)
+	}
+
+	return e.callFunction(fn, args, node)
+}
+
+func (e *Evaluator) callFunction(fn *Function, args []interface{}, node *parse.CallExpressionNode) (interface{}, error) {
+	loc := node.LParen.Loc
+	if len(args) != len(fn.Params) {
+		return nil, e.errorAt(loc, "%s expects %d arguments, got %d", fn.Name(), len(fn.Params), len(args))
+	}
+
+	scope := parse.NewScope(fn.Scope)
+	for i, param := range fn.Node.Params {
+		val, ok := convertTo(fn.Params[i], args[i])
+		if !ok {
+			return nil, e.typeErrorAt(loc, "cannot use %s value as %s in argument %s of %s", typeOf(args[i]), fn.Params[i], param.Name.Val, fn.Name())
+		}
+		scope.DefineTyped(param.Name.Val, val, fn.Params[i])
+	}
+
+	if err := e.checkDepth(loc); err != nil {
+		return nil, err
+	}
+
+	caller := e.scope
+	e.scope = scope
+	e.calls = append(e.calls, call{function: fn.Name(), loc: loc, scope: caller, locals: scope})
+	_, err := e.visitNode(fn.Node.Body)
+	e.calls = e.calls[:len(e.calls)-1]
+	e.scope = caller
+
+	var result interface{}
+	if ret, ok := err.(*returnSignal); ok {
+		result = ret.value
+	} else if signal, ok := err.(*loopSignal); ok {
+		// break and continue must not leak into the loops of the caller
+		return nil, e.errorAt(signal.token.Loc, "%s is not in a loop", signal.token.Val)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if fn.ReturnType == nil {
+		return result, nil
+	}
+
+	converted, ok := convertTo(fn.ReturnType, result)
+	if !ok {
+		return nil, e.typeErrorAt(loc, "%s must return %s, got %s", fn.Name(), fn.ReturnType, typeOf(result))
+	}
+	return converted, nil
+}
+
+func (e *Evaluator) visitFunctionNode(node *parse.FunctionNode) (interface{}, error) {
+	fn := &Function{Node: node, Scope: e.scope, Params: make([]*Type, len(node.Params))}
+	for i, param := range node.Params {
+		typ, err := resolveType(e, param.Type)
+		if err != nil {
+			return nil, err
+		}
+		fn.Params[i] = typ
+	}
+
+	if node.ReturnType != nil {
+		typ, err := resolveType(e, node.ReturnType)
+		if err != nil {
+			return nil, err
+		}
+		fn.ReturnType = typ
+	}
+
+	// named functions are declarations, anonymous ones are just values
+	if node.Name.Val != "" {
+		if _, ok := e.scope.ResolveLocal(node.Name.Val); ok {
+			return nil, e.errorAt(node.Name.Loc, "function %s already defined", node.Name.Val)
+		}
+		e.scope.Define(node.Name.Val, fn)
+	}
+
+	return fn, nil
+}
+
+func (e *Evaluator) visitReturnStatementNode(node *parse.ReturnStatementNode) (interface{}, error) {
+	var val interface{}
+	if node.Expression != nil {
+		var err error
+		val, err = e.visitNode(node.Expression)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, &returnSignal{value: val}
+}
+
+//////
+
+func (e *Evaluator) visitIfStatementNode(node *parse.IfStatementNode) (interface{}, error) {
+	condition, err := e.visitNode(node.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, isBool := condition.(bool)
+	if !isBool {
+		return nil, e.typeErrorAt(node.IfToken.Loc, "if condition must be bool, got %s", typeOf(condition))
+	}
+	if ok {
+		return e.visitNode(node.Body)
+	} else if node.Else != nil {
+		return e.visitNode(node.Else)
+	}
+
+	return nil, nil
+}
+
+func (e *Evaluator) visitForStatementNode(node *parse.ForStatementNode) (interface{}, error) {
+	// variables declared by init statement live in their own scope
+	e.pushScope()
+	defer e.popScope()
+
+	if node.Init != nil {
+		if _, err := e.visitNode(node.Init); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		if node.Condition != nil {
+			condition, err := e.visitNode(node.Condition)
+			if err != nil {
+				return nil, err
+			}
+			ok, isBool := condition.(bool)
+			if !isBool {
+				loc := node.ForToken.Loc
+				return nil, e.typeErrorAt(loc, "loop condition must be bool, got %s", typeOf(condition))
+			}
+			if !ok {
+				break
+			}
+		}
+
+		if _, err := e.visitNode(node.Body); err != nil {
+			signal, ok := err.(*loopSignal)
+			if !ok {
+				return nil, err
+			}
+			if signal.token.Kind == parse.BREAK {
+				break
+			}
+		}
+
+		if node.Post != nil {
+			if _, err := e.visitNode(node.Post); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (e *Evaluator) visitElseStatementNode(node *parse.ElseStatementNode) (interface{}, error) {
+	return e.visitNode(node.Body)
+}
+
+// visitProgramNode evaluates top level statements in the evaluator's scope and returns the last value
+func (e *Evaluator) visitProgramNode(node *parse.ProgramNode) (interface{}, error) {
+	var response any
+	for _, statement := range node.Nodes {
+		val, err := e.visitNode(statement)
+		if err != nil {
+			return nil, err
+		}
+		response = val
+	}
+
+	return response, nil
+}
+
+func (e *Evaluator) visitBlockStatementNode(node *parse.BlockStatementNode) (interface{}, error) {
+	e.pushScope()
+	defer e.popScope()
+
+	var response any
+	for _, statement := range node.Nodes {
+		val, err := e.visitNode(statement)
+		if err != nil {
+			return nil, err
+		}
+		response = val
+	}
+
+	return response, nil
+}
+
+func (e *Evaluator) visitVariableDeclarationNode(node *parse.VariableDeclarationStatementNode) (interface{}, error) {
+	var val interface{}
+	var err error
+	if node.Expression != nil {
+		val, err = e.visitNode(node.Expression)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := e.scope.ResolveLocal(node.Identifier.Val); ok {
+		return nil, e.errorAt(node.Identifier.Loc, "variable %s already defined", node.Identifier.Val)
+	}
+
+	if node.HasTypeToken {
+		// if the type is specified, check if the value is of that type
+		typ, err := resolveType(e, node.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		converted, ok := convertTo(typ, val)
+		if !ok {
+			return nil, e.typeErrorAt(node.Identifier.Loc, "cannot use %s value as %s in declaration of %s", typeOf(val), typ, node.Identifier.Val)
+		}
+		e.scope.DefineTyped(node.Identifier.Val, converted, typ)
+		return converted, nil
+	}
+
+	e.scope.Define(node.Identifier.Val, val)
+	return val, nil
+}
+
+// lookupType finds a declared type in the scopes of the evaluator
+func (e *Evaluator) lookupType(name string) (*Type, bool) {
+	typ, ok := e.scope.ResolveType(name)
+	if !ok {
+		return nil, false
+	}
+	return typ.(*Type), true
+}
+
+func (e *Evaluator) defineType(name string, typ *Type) {
+	e.scope.DefineType(name, typ)
+}
+
+func (e *Evaluator) visitTypeDeclarationNode(node *parse.TypeDeclarationNode) (interface{}, error) {
+	loc := node.Name.Loc
+	if _, ok := builtinTypes[node.Name.Val]; ok {
+		return nil, e.errorAt(loc, "cannot redeclare builtin type %s", node.Name.Val)
+	}
+	if _, ok := e.scope.ResolveLocalType(node.Name.Val); ok {
+		return nil, e.errorAt(loc, "type %s already defined", node.Name.Val)
+	}
+
+	_, err := resolveTypeDeclaration(e, node)
+	return nil, err
+}
@@ -0,0 +1,114 @@
+package eval
+
+import "myProgrammingLanguage/parse"
+
+// Hook is called by the tree walker before it visits a node, debuggers pause the evaluation by blocking
+// in it. An error returned by the hook stops the evaluation with that error.
+type Hook func(step *Step) error
+
+// Step is the position of the tree walker passed to hooks, it is only valid until the hook returns
+type Step struct {
+	// Node is the node about to be visited
+	Node parse.Node
+	// Scope is the innermost scope of the node
+	Scope *parse.Scope
+	// Depth is the number of active calls, the calls of modules being imported included
+	Depth int
+	e     *Evaluator
+}
+
+// StackFrame is a frame of the call stack of a step, Scope is the innermost scope of the frame.
+// Locals is the scope of the parameters of the function, it is nil for the program.
+type StackFrame struct {
+	Frame
+	Scope  *parse.Scope
+	Locals *parse.Scope
+}
+
+// SetHook sets the hook called before every node the evaluator visits
+func (e *Evaluator) SetHook(hook Hook) {
+	e.hook = hook
+}
+
+// SetHook sets the hook of the tree walkers running the programs and their modules, the vm has no hooks
+func (i *Importer) SetHook(hook Hook) {
+	i.hook = hook
+}
+
+// Loc returns the location of the first token of the node
+func (s *Step) Loc() parse.TokenLocation {
+	return parse.FirstToken(s.Node).Loc
+}
+
+// Frames returns the call stack starting from the innermost frame
+func (s *Step) Frames() []StackFrame {
+	frames := s.e.stackTrace(s.Loc())
+	stack := make([]StackFrame, len(frames))
+	scope := s.Scope
+	for i, frame := range frames {
+		stack[i] = StackFrame{Frame: frame, Scope: scope}
+		if i < len(s.e.calls) {
+			// calls keep the scope of their caller
+			call := s.e.calls[len(s.e.calls)-1-i]
+			stack[i].Locals, scope = call.locals, call.scope
+		}
+	}
+	return stack
+}
+
+// Eval evaluates the source like a watch expression in the scope, the hook isn't called for it.
+// The scope is usually the one of a frame of the step. Only single expressions without assignments
+// are evaluated, so that inspecting the program doesn't change it, but the functions they call run
+// like in the program and change the variables they assign.
+func (s *Step) Eval(src string, scope *parse.Scope) (any, error) {
+	parser := parse.NewParser("<watch>", src)
+	tree, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if parser.Errors.HasErrors() {
+		return nil, &SyntaxError{Errors: parser.Errors.GetErrors()}
+	}
+	if err := watchError(tree.Root.(*parse.ProgramNode)); err != nil {
+		return nil, &SyntaxError{Errors: []parse.Err{*err}}
+	}
+
+	evaluator := NewEvaluator(tree, scope)
+	evaluator.ctx = s.e.ctx
+	evaluator.imports = s.e.imports
+	return evaluator.Evaluate()
+}
+
+// watchError returns the error of watch expressions which aren't a single expression or assign variables
+func watchError(program *parse.ProgramNode) *parse.Err {
+	newErr := func(node parse.Node, msg string) *parse.Err {
+		token := parse.FirstToken(node)
+		return &parse.Err{File: token.Loc.Start.Filename, Len: len(token.Val), Loc: token.Loc, Msg: msg, Kind: parse.Error}
+	}
+	if len(program.Nodes) != 1 {
+		return newErr(program, "watch expressions must be a single expression")
+	}
+	switch n := program.Nodes[0].(type) {
+	case *parse.VariableDeclarationStatementNode, *parse.IfStatementNode, *parse.ReturnStatementNode, *parse.ForStatementNode,
+		*parse.BranchStatementNode, *parse.TypeDeclarationNode, *parse.ImportNode:
+		return newErr(n, "watch expressions must be a single expression")
+	case *parse.FunctionNode:
+		if n.Name.Val != "" {
+			return newErr(n, "watch expressions must be a single expression")
+		}
+	}
+
+	var err *parse.Err
+	parse.Inspect(program, func(node parse.Node) bool {
+		if node.Kind() == parse.NodeAssignmentExpression && err == nil {
+			err = newErr(node, "watch expressions can't assign variables")
+		}
+		return err == nil
+	})
+	return err
+}
+
+// FormatValue formats a value like the elements of arrays are formatted, strings are quoted
+func FormatValue(val any) string {
+	return formatValue(val)
+}
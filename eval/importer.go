@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"context"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"path/filepath"
+)
+
+// Importer runs programs and the modules they import. Every module is evaluated once, in its own
+// global scope with the engine and the limits of the importer. The steps of the modules count
+// towards the step limit of the program importing them.
+type Importer struct {
+	Loader *module.Loader
+	Engine Engine
+	Limits Limits
+	// modules holds the evaluated modules by file
+	modules map[string]*Module
+	chain   module.Chain
+	hook    Hook
+	// universe is the outer scope of the global scopes of the modules
+	universe *parse.Scope
+	// depth is the number of calls active when the module being imported was imported
+	depth int
+	// steps is the number of steps taken when the module being imported was imported, the engine
+	// running a tree continues counting from it and leaves its count in it
+	steps int64
+}
+
+func NewImporter(loader *module.Loader, engine Engine, limits Limits) *Importer {
+	return &Importer{Loader: loader, Engine: engine, Limits: limits, modules: map[string]*Module{}, universe: universe}
+}
+
+// Run runs the tree with the engine of the importer in the scope, see the function Run
+func (i *Importer) Run(ctx context.Context, name string, tree *parse.SyntaxTree, scope *parse.Scope) (any, error) {
+	// the file of the program is part of the chain, so that modules importing it are reported as cycles
+	if name != "" && i.chain.Enter(filepath.Clean(name)) == nil {
+		defer i.chain.Leave()
+	}
+	i.steps = 0
+	return i.run(ctx, name, tree, scope)
+}
+
+func (i *Importer) run(ctx context.Context, name string, tree *parse.SyntaxTree, scope *parse.Scope) (any, error) {
+	if i.Engine == BytecodeVM {
+		proto, err := Compile(name, tree, scope)
+		if err != nil {
+			return nil, err
+		}
+		vm := NewVM(scope)
+		vm.SetLimits(i.Limits)
+		vm.imports = i
+		vm.steps = i.steps
+		defer func() { i.steps = vm.steps }()
+		return vm.execute(ctx, proto)
+	}
+
+	evaluator := NewEvaluator(tree, scope)
+	evaluator.ctx = ctx
+	evaluator.SetLimits(i.Limits)
+	evaluator.imports = i
+	evaluator.hook = i.hook
+	evaluator.baseDepth = i.depth
+	evaluator.steps = i.steps
+	defer func() { i.steps = evaluator.steps }()
+	return evaluator.Evaluate()
+}
+
+// importModule evaluates the module of the import if it isn't evaluated yet and defines it in the scope.
+// Errors finding the module are reported at the import by the call stack s.
+func (i *Importer) importModule(ctx context.Context, s *callStack, node *parse.ImportNode, scope *parse.Scope) error {
+	mod, err := i.load(ctx, s, node)
+	if err != nil {
+		return err
+	}
+
+	// importing a module again like the repl may do is fine
+	if previous, ok := scope.ResolveLocal(node.Name()); ok && previous != mod {
+		return s.errorAt(node.Path.Token.Loc, "module %s already defined", node.Name())
+	}
+	scope.Define(node.Name(), mod)
+	return nil
+}
+
+func (i *Importer) load(ctx context.Context, s *callStack, node *parse.ImportNode) (*Module, error) {
+	loc := node.Path.Token.Loc
+	file, err := i.Loader.Load(node.Path.Val, node.ImportToken.Loc.Start.Filename)
+	if err != nil {
+		return nil, s.errorAt(loc, "%w", err)
+	}
+	if mod, ok := i.modules[file.Path]; ok {
+		return mod, nil
+	}
+	if len(file.Errors) > 0 {
+		return nil, &SyntaxError{Errors: file.Errors}
+	}
+	if err := i.chain.Enter(file.Path); err != nil {
+		return nil, s.errorAt(loc, "%w", err)
+	}
+	defer i.chain.Leave()
+
+	mod := &Module{Name: node.Name(), Path: file.Path, Scope: parse.NewScope(i.universe)}
+	depth := i.depth
+	i.depth += len(s.calls) + 1
+	i.steps = s.steps
+	_, err = i.run(ctx, file.Path, file.Tree, mod.Scope)
+	i.depth = depth
+	s.steps = i.steps
+	if err != nil {
+		return nil, err
+	}
+	i.modules[file.Path] = mod
+	return mod, nil
+}
+
+// moduleMember returns the exported member of the module named like the field
+func (s *callStack) moduleMember(field parse.Token, mod *Module) (interface{}, error) {
+	// the modules of the standard library have no file and export all of their members
+	if mod.Path != "" && !module.IsExported(field.Val) {
+		return nil, s.errorAt(field.Loc, "cannot refer to unexported name %s.%s", mod.Name, field.Val)
+	}
+	val, ok := mod.Scope.ResolveLocal(field.Val)
+	if !ok {
+		return nil, s.errorAt(field.Loc, "module %s has no exported name %s", mod.Name, field.Val)
+	}
+	return val, nil
+}
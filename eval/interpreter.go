@@ -0,0 +1,157 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Engine selects how an Interpreter runs code
+type Engine int
+
+const (
+	// TreeWalker evaluates the syntax tree directly
+	TreeWalker Engine = iota
+	// BytecodeVM compiles the syntax tree and runs the bytecode on the VM
+	BytecodeVM
+)
+
+// ParseEngine converts the engine names "tree" and "vm" used by the command line
+func ParseEngine(name string) (Engine, error) {
+	switch name {
+	case "tree":
+		return TreeWalker, nil
+	case "vm":
+		return BytecodeVM, nil
+	}
+	return TreeWalker, fmt.Errorf("unknown engine %q, expected tree or vm", name)
+}
+
+// Options configures an Interpreter
+type Options struct {
+	// Name is used as the file name in errors of Eval, it defaults to "<eval>"
+	Name string
+	// Engine runs the code, the tree walker by default
+	Engine Engine
+	// Globals are defined in the global scope before any code runs, see SetGlobal
+	Globals map[string]any
+	// Limits bound the work of every evaluation, use the context of Eval for a deadline
+	Limits Limits
+	// SearchPath holds the directories searched for imported modules, the directories of the PALM_PATH
+	// environment variable by default
+	SearchPath []string
+	// AllowIO defines the io module and the print functions. Without it scripts and the modules they import
+	// can't read or write files or use the standard streams.
+	AllowIO bool
+}
+
+// Interpreter evaluates palm code for Go host programs.
+// The global scope is kept between evaluations, so definitions of one Eval are visible to the next one.
+type Interpreter struct {
+	name    string
+	scope   *parse.Scope
+	tree    *parse.SyntaxTree
+	imports *Importer
+}
+
+func NewInterpreter(opts Options) (*Interpreter, error) {
+	searchPath := opts.SearchPath
+	if searchPath == nil {
+		searchPath = module.DefaultSearchPath()
+	}
+	i := &Interpreter{
+		name:    opts.Name,
+		imports: NewImporter(module.NewLoader(searchPath), opts.Engine, opts.Limits),
+	}
+	if !opts.AllowIO {
+		i.imports.universe = sandbox
+	}
+	i.scope = parse.NewScope(i.imports.universe)
+	if i.name == "" {
+		i.name = "<eval>"
+	}
+
+	for name, val := range opts.Globals {
+		if err := i.SetGlobal(name, val); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+// SyntaxError holds the errors of the parser
+type SyntaxError struct {
+	Errors []parse.Err
+}
+
+func (s *SyntaxError) Error() string {
+	messages := make([]string, len(s.Errors))
+	for i, err := range s.Errors {
+		messages[i] = err.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Eval parses and evaluates src and returns the value of the last statement.
+// Evaluation stops with a LimitExceeded error if ctx is done.
+func (i *Interpreter) Eval(ctx context.Context, src string) (any, error) {
+	return i.eval(ctx, i.name, src)
+}
+
+// EvalFile evaluates the palm file at path
+func (i *Interpreter) EvalFile(path string) (any, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return i.eval(context.Background(), path, string(src))
+}
+
+func (i *Interpreter) eval(ctx context.Context, name, src string) (any, error) {
+	parser := parse.NewParser(name, src)
+	tree, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	if parser.Errors.HasErrors() {
+		return nil, &SyntaxError{Errors: parser.Errors.GetErrors()}
+	}
+
+	i.tree = tree
+	return i.imports.Run(ctx, name, tree, i.scope)
+}
+
+// Run runs the tree with the given engine in the scope, the evaluation is bounded by the limits and ctx.
+// Imports are looked up in the default search path, use an Importer to configure it.
+func Run(ctx context.Context, engine Engine, name string, tree *parse.SyntaxTree, scope *parse.Scope, limits Limits) (any, error) {
+	return NewImporter(module.NewLoader(module.DefaultSearchPath()), engine, limits).Run(ctx, name, tree, scope)
+}
+
+// SetGlobal defines or overwrites a global variable, the Go value is converted by ToValue
+// and Go functions are registered like RegisterFunc does.
+func (i *Interpreter) SetGlobal(name string, val any) error {
+	if reflect.ValueOf(val).Kind() == reflect.Func {
+		return i.RegisterFunc(name, val)
+	}
+
+	converted, err := ToValue(val)
+	if err != nil {
+		return fmt.Errorf("global %s: %w", name, err)
+	}
+	i.scope.Define(name, converted)
+	return nil
+}
+
+// RegisterFunc exposes a Go function to palm code as a global function, see NewNativeFunction
+func (i *Interpreter) RegisterFunc(name string, fn any) error {
+	return RegisterFunc(i.scope, name, fn)
+}
+
+// GetGlobal returns the palm value of a global variable, use Convert to get a Go value of it
+func (i *Interpreter) GetGlobal(name string) (any, bool) {
+	return i.scope.ResolveLocal(name)
+}
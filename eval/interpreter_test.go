@@ -0,0 +1,131 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpreterKeepsGlobals(t *testing.T) {
+	for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+		interpreter, err := NewInterpreter(Options{Engine: engine, Globals: map[string]any{"limit": 10, "names": []string{"a", "b"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, src := range []string{"total := 0", "for i := 0; i < limit; i += 1 { total += i }", "fn twice(int n) int { return 2 * n }"} {
+			if _, err := interpreter.Eval(context.Background(), src); err != nil {
+				t.Fatalf("engine %d: %s: %v", engine, src, err)
+			}
+		}
+		result, err := interpreter.Eval(context.Background(), "twice(total) + len(names)")
+		if got, convErr := Convert[int](result); err != nil || convErr != nil || got != 92 {
+			t.Errorf("engine %d: got %v, %v, want 92", engine, result, err)
+		}
+		if total, ok := interpreter.GetGlobal("total"); !ok || total != int64(45) {
+			t.Errorf("engine %d: GetGlobal(total) = %v, %v, want 45", engine, total, ok)
+		}
+		if err := interpreter.SetGlobal("limit", 3); err != nil {
+			t.Fatal(err)
+		}
+		if result, err := interpreter.Eval(context.Background(), "limit * 2"); err != nil || result != int64(6) {
+			t.Errorf("engine %d: got %v, %v after SetGlobal, want 6", engine, result, err)
+		}
+		if err := interpreter.SetGlobal("ch", make(chan int)); err == nil {
+			t.Errorf("engine %d: SetGlobal of a channel: got no error", engine)
+		}
+	}
+}
+
+// TestInterpreterAssignment checks that embedders get the same semantics for assignments to
+// undeclared variables on both engines, Eval doesn't run the checker which reports them
+func TestInterpreterAssignment(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"found = 5\nfound", "5"},
+		{"if true { found = 5 }", "error <eval>:1:11: error: variable found not defined"},
+		{"fn f() { g = 5 }\nf()", "error <eval>:1:10: error: variable g not defined"},
+		{"for i := 0; i < 2; i += 1 { last = i }", "error <eval>:1:29: error: variable last not defined"},
+		{"n := 1\nif true { n = 2 }\nn", "2"},
+		{"x += 1", "error <eval>:1:1: error: variable x not defined"},
+	}
+	for _, test := range tests {
+		for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+			interpreter, err := NewInterpreter(Options{Engine: engine})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := interpreter.Eval(context.Background(), test.src)
+			got := fmt.Sprint(result)
+			if err != nil {
+				got = "error " + err.Error()
+			}
+			if got != test.want {
+				t.Errorf("engine %d: %q: got %s, want %s", engine, test.src, got, test.want)
+			}
+		}
+	}
+}
+
+func TestInterpreterErrors(t *testing.T) {
+	for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+		interpreter, err := NewInterpreter(Options{Engine: engine, Name: "script.pd"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = interpreter.Eval(context.Background(), "x := )")
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || err.Error() != "script.pd:1:6: error: expected expression got RPAREN" {
+			t.Errorf("engine %d: got %v, want a syntax error in script.pd", engine, err)
+		}
+
+		_, err = interpreter.Eval(context.Background(), "fn div(int a, int b) int { return a / b }\ndiv(1, 0)")
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) || !errors.Is(err, DivisionByZero) || runtimeErr.Loc.Start.Filename != "script.pd" || len(runtimeErr.Stack) != 2 {
+			t.Errorf("engine %d: got %v, want a division by zero in div", engine, err)
+		}
+		if _, err := interpreter.Eval(context.Background(), `1 + "a"`); !errors.Is(err, TypeError) {
+			t.Errorf("engine %d: got %v, want a type error", engine, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := interpreter.Eval(ctx, "for { }"); !errors.Is(err, LimitExceeded) {
+			t.Errorf("engine %d: got %v for a cancelled context, want a limit error", engine, err)
+		}
+		// the interpreter can still be used after errors
+		if result, err := interpreter.Eval(context.Background(), "div(6, 3)"); err != nil || result != int64(2) {
+			t.Errorf("engine %d: got %v, %v after errors, want 2", engine, result, err)
+		}
+	}
+}
+
+func TestInterpreterEvalFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.pd"), []byte("fn Square(int n) int { return n * n }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "main.pd")
+	if err := os.WriteFile(path, []byte("import \"./lib\"\nlib.Square(7)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+		interpreter, err := NewInterpreter(Options{Engine: engine, SearchPath: []string{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, err := interpreter.EvalFile(path); err != nil || result != int64(49) {
+			t.Errorf("engine %d: got %v, %v, want 49", engine, result, err)
+		}
+		if _, err := interpreter.EvalFile(filepath.Join(dir, "missing.pd")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("engine %d: got %v for a missing file", engine, err)
+		}
+		if _, err := interpreter.Eval(context.Background(), `import "./lib"`); err == nil || !strings.Contains(err.Error(), "cannot find module ./lib.pd") {
+			t.Errorf("engine %d: got %v, want imports of Eval relative to the working directory", engine, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"myProgrammingLanguage/parse"
+)
+
+// Limits bound the work of the code run by an engine, so that untrusted scripts can't hang or exhaust
+// the host program. Zero fields mean no limit, except for MaxDepth: the call depth is always bounded, so
+// that recursion can't crash the host before the other limits are reached. Exceeding a limit stops the
+// evaluation with a RuntimeError of kind LimitExceeded, the wall-clock limit is the deadline of the context
+// passed to the engine.
+type Limits struct {
+	// MaxSteps is the number of evaluation steps, nodes visited by the tree walker and instructions run by the vm,
+	// of the program and the modules it imports together
+	MaxSteps int64
+	// MaxDepth is the maximum depth of nested calls of palm functions, zero and larger values mean the
+	// default of 10000 calls
+	MaxDepth int
+	// MaxCollectionSize is the maximum number of elements of an array and of bytes of a string
+	MaxCollectionSize int
+}
+
+// maxCallDepth is the call depth used when MaxDepth is zero or larger. Deeper recursion would overflow the
+// Go stack of the tree walker, which can't be recovered, and grow the frames of the vm without bound.
+const maxCallDepth = 10000
+
+// contextCheckInterval is the number of steps between checks of the context
+const contextCheckInterval = 1024
+
+// step counts an evaluation step at loc and checks the step limit and the context
+func (s *callStack) step(ctx context.Context, loc func() parse.TokenLocation) error {
+	s.steps++
+	if s.limits.MaxSteps > 0 && s.steps > s.limits.MaxSteps {
+		return s.newError(LimitExceeded, loc(), "step limit of %d exceeded", s.limits.MaxSteps)
+	}
+	// the first step is checked too, so that nothing runs with a context which is already done
+	if s.steps%contextCheckInterval == 1 && ctx.Err() != nil {
+		return s.contextError(ctx, loc())
+	}
+	return nil
+}
+
+// contextError returns the LimitExceeded error of the done context at loc
+func (s *callStack) contextError(ctx context.Context, loc parse.TokenLocation) error {
+	err := ctx.Err()
+	msg := "evaluation canceled"
+	if errors.Is(err, context.DeadlineExceeded) {
+		msg = "time limit exceeded"
+	}
+	limitErr := s.newError(LimitExceeded, loc, msg)
+	limitErr.(*RuntimeError).Err = err
+	return limitErr
+}
+
+// limitsKey is the context key of the limits passed to builtins
+type limitsKey struct{}
+
+// maxCollectionSize returns the maximum collection size of the engine calling a builtin with ctx, 0 for no limit
+func maxCollectionSize(ctx context.Context) int {
+	limits, _ := ctx.Value(limitsKey{}).(Limits)
+	return limits.MaxCollectionSize
+}
+
+// checkDepth returns an error if a call at loc would exceed the maximum call depth
+func (s *callStack) checkDepth(loc parse.TokenLocation) error {
+	max := s.limits.MaxDepth
+	if max <= 0 || max > maxCallDepth {
+		max = maxCallDepth
+	}
+	if len(s.calls) >= max {
+		return s.newError(LimitExceeded, loc, "maximum call depth of %d exceeded", max)
+	}
+	return nil
+}
+
+// checkSize returns an error if a collection of the given size created at loc is larger than the maximum
+// collection size, what describes the collection with a verb for the size like "array of %d elements"
+func (s *callStack) checkSize(loc parse.TokenLocation, size int, what string) error {
+	if s.limits.MaxCollectionSize > 0 && size > s.limits.MaxCollectionSize {
+		return s.newError(LimitExceeded, loc, what+" exceeds the maximum collection size of %d", size, s.limits.MaxCollectionSize)
+	}
+	return nil
+}
+
+// checkValueSize checks the size of arrays and strings with checkSize, other values are never too large
+func (s *callStack) checkValueSize(loc parse.TokenLocation, val interface{}) error {
+	switch v := val.(type) {
+	case *Array:
+		return s.checkSize(loc, len(v.Elements), "array of %d elements")
+	case string:
+		return s.checkSize(loc, len(v), "string of %d bytes")
+	}
+	return nil
+}
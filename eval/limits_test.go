@@ -0,0 +1,74 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnboundedRecursion(t *testing.T) {
+	src := "fn f(int n) int { return f(n + 1) }\nf(0)"
+	for _, limits := range []Limits{{}, {MaxSteps: 1 << 40}, {MaxDepth: 1 << 30}} {
+		for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+			interpreter, err := NewInterpreter(Options{Engine: engine, Limits: limits})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = interpreter.Eval(context.Background(), src)
+			if !errors.Is(err, LimitExceeded) || !strings.Contains(err.Error(), "maximum call depth of 10000 exceeded") {
+				t.Errorf("engine %d, limits %+v: got %v, want the default call depth error", engine, limits, err)
+			}
+		}
+	}
+}
+
+func TestStackTraceElidesDeepStacks(t *testing.T) {
+	interpreter, err := NewInterpreter(Options{Limits: Limits{MaxDepth: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = interpreter.Eval(context.Background(), "fn f(int n) int { return f(n + 1) }\nf(0)")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	trace := runtimeErr.StackTrace()
+	if lines := strings.Count(trace, "\n"); lines != 2*stackTraceEnds+1 {
+		t.Errorf("got %d lines, want %d:\n%s", lines, 2*stackTraceEnds+1, trace)
+	}
+	if !strings.Contains(trace, "... 81 frames elided") {
+		t.Errorf("got trace without the elided frames:\n%s", trace)
+	}
+}
+
+func TestImportsShareStepLimit(t *testing.T) {
+	dir := t.TempDir()
+	spin := "i := 0\nfor i < 100 { i += 1 }\n"
+	if err := os.WriteFile(filepath.Join(dir, "spin.pd"), []byte(spin), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the limits let one loop run but not the loop of the module and the loop of the program
+	for engine, steps := range map[Engine]int64{TreeWalker: 1000, BytecodeVM: 1500} {
+		run := func(src string) error {
+			name := filepath.Join(dir, "main.pd")
+			tree, err := parse.NewParser(name, src).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = NewImporter(module.NewLoader(nil), engine, Limits{MaxSteps: steps}).Run(context.Background(), name, tree, NewGlobalScope())
+			return err
+		}
+		if err := run(spin); err != nil {
+			t.Fatalf("engine %d: got %v running one loop", engine, err)
+		}
+		err := run("import \"./spin\"\n" + spin)
+		if !errors.Is(err, LimitExceeded) || !strings.HasPrefix(err.Error(), filepath.Join(dir, "main.pd")) {
+			t.Errorf("engine %d: got %v, want the step limit exceeded in main.pd", engine, err)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myProgrammingLanguage/parse"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// NativeFunction is a Go function which can be called from palm code.
+// Arguments are converted with FromValue and results with ToValue.
+type NativeFunction struct {
+	name string
+	fn   reflect.Value
+	// builtin is set instead of fn for the functions of the standard library, see newBuiltin
+	builtin builtinFunc
+	// signature is the palm signature of builtins like "(interface... values)"
+	signature string
+}
+
+// builtinFunc is a function of the standard library, it gets the palm values of the arguments as they
+// are and checks them itself
+type builtinFunc func(ctx context.Context, args []any) (any, error)
+
+// nativeError is an error of a native function which engines report as a RuntimeError of the kind,
+// like a TypeError for arguments of the wrong type
+type nativeError struct {
+	kind RuntimeErrorKind
+	msg  string
+}
+
+func newNativeError(kind RuntimeErrorKind, format string, args ...any) error {
+	return &nativeError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+func (n *nativeError) Error() string {
+	return n.msg
+}
+
+// NewNativeFunction wraps a Go function. The function may return nothing, a value,
+// an error or a value and an error; a non nil error becomes a palm runtime error.
+func NewNativeFunction(name string, fn any) (*NativeFunction, error) {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return nil, fmt.Errorf("%s: expected a Go function, got %T", name, fn)
+	}
+
+	t := rv.Type()
+	switch t.NumOut() {
+	case 0, 1:
+	case 2:
+		if t.Out(1) != errorType {
+			return nil, fmt.Errorf("%s: second result must be an error", name)
+		}
+	default:
+		return nil, fmt.Errorf("%s: functions can return at most a value and an error", name)
+	}
+	return &NativeFunction{name: name, fn: rv}, nil
+}
+
+func newBuiltin(name, signature string, fn builtinFunc) *NativeFunction {
+	return &NativeFunction{name: name, builtin: fn, signature: signature}
+}
+
+// RegisterFunc defines a Go function as a palm function in given scope, see NewNativeFunction
+func RegisterFunc(scope *parse.Scope, name string, fn any) error {
+	native, err := NewNativeFunction(name, fn)
+	if err != nil {
+		return err
+	}
+	scope.Define(name, native)
+	return nil
+}
+
+func (n *NativeFunction) Name() string {
+	return n.name
+}
+
+func (n *NativeFunction) String() string {
+	if n.builtin != nil {
+		return "fn " + n.name + n.signature
+	}
+	return "fn " + n.name + n.fn.Type().String()[len("func"):]
+}
+
+// Call converts the palm arguments, calls the Go function and converts its result back
+func (n *NativeFunction) Call(args []any) (any, error) {
+	return n.call(context.Background(), args)
+}
+
+// call calls the function like Call, builtins get the context of the engine
+func (n *NativeFunction) call(ctx context.Context, args []any) (any, error) {
+	if n.builtin != nil {
+		return n.builtin(ctx, args)
+	}
+
+	t := n.fn.Type()
+	fixed := t.NumIn()
+	if t.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, fmt.Errorf("%s expects at least %d arguments, got %d", n.name, fixed, len(args))
+		}
+	} else if len(args) != fixed {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", n.name, fixed, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		paramType := t.In(min(i, t.NumIn()-1))
+		if t.IsVariadic() && i >= fixed {
+			paramType = paramType.Elem()
+		}
+		// arguments of palm types are reported like for palm functions
+		if typ, err := goTypeToType(paramType); err == nil {
+			if _, ok := convertTo(typ, arg); !ok {
+				return nil, newNativeError(TypeError, "cannot use %s value as %s in argument %d of %s", typeOf(arg), typ, i+1, n.name)
+			}
+		}
+		converted, err := FromValue(arg, paramType)
+		if err != nil {
+			return nil, newNativeError(TypeError, "argument %d of %s: %s", i+1, n.name, err)
+		}
+		in[i] = converted
+	}
+
+	out := n.fn.Call(in)
+	if len(out) > 0 && t.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, fmt.Errorf("%s: %w", n.name, err)
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	result, err := ToValue(out[0].Interface())
+	if err != nil {
+		return nil, fmt.Errorf("result of %s: %w", n.name, err)
+	}
+	return result, nil
+}
+
+// callNative calls the native function at loc, errors of the function are reported as runtime errors
+// and the result is checked against the limits
+func (s *callStack) callNative(ctx context.Context, loc parse.TokenLocation, fn *NativeFunction, args []any) (any, error) {
+	if fn.builtin != nil && s.limits.MaxCollectionSize > 0 {
+		// builtins like io.readFile stop reading at the limit
+		ctx = context.WithValue(ctx, limitsKey{}, s.limits)
+	}
+	s.calls = append(s.calls, call{function: fn.Name(), loc: loc})
+	result, err := fn.call(ctx, args)
+	s.calls = s.calls[:len(s.calls)-1]
+	if err != nil {
+		var nativeErr *nativeError
+		switch {
+		case errors.As(err, &nativeErr):
+			return nil, s.newError(nativeErr.kind, loc, "%s", nativeErr)
+		case ctx.Err() != nil:
+			// builtins like time.sleep stop when the context is done
+			return nil, s.contextError(ctx, loc)
+		}
+		return nil, s.errorAt(loc, "%w", err)
+	}
+	if err := s.checkValueSize(loc, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y int
+}
+
+func TestNativeFunctions(t *testing.T) {
+	globals := map[string]any{
+		"add":  func(a, b int) int { return a + b },
+		"join": func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"atoi": strconv.Atoi,
+		"sum": func(values []float64) float64 {
+			total := 0.0
+			for _, v := range values {
+				total += v
+			}
+			return total
+		},
+		"words": strings.Fields,
+		"norm":  func(p point) int { return p.X*p.X + p.Y*p.Y },
+		"kind":  func(v any) string { return fmt.Sprintf("%T", v) },
+		"small": func(n int8) int8 { return n },
+		"noop":  func() {},
+	}
+	tests := []struct {
+		src, want string
+	}{
+		{"add(2, 3)", "5"},
+		{`join(", ", "a", "b", "c")`, "a, b, c"},
+		{`join("-")`, ""},
+		{`atoi("42") + 1`, "43"},
+		{"float[] values = [1.5, 2.5]\nsum(values)", "4"},
+		{`len(words(" a b  c "))`, "3"},
+		{"type point = struct { int x\nint y }\nnorm(point{x: 3, y: 4})", "25"},
+		{`kind(1) + kind("a") + kind([1])`, "int64string[]interface {}"},
+		{"noop()", "<nil>"},
+		{"f := add\nf(1, 1)", "2"},
+		{`add("a", 1)`, "error <eval>:1:4: error: cannot use string value as int in argument 1 of add"},
+		{"add(1)", "error <eval>:1:4: error: add expects 2 arguments, got 1"},
+		{"join()", "error <eval>:1:5: error: join expects at least 1 arguments, got 0"},
+		{`atoi("x")`, `error <eval>:1:5: error: atoi: strconv.Atoi: parsing "x": invalid syntax`},
+		{"small(300)", "error <eval>:1:6: error: argument 1 of small: 300 overflows int8"},
+	}
+	for _, test := range tests {
+		for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+			interpreter, err := NewInterpreter(Options{Engine: engine, Globals: globals})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := interpreter.Eval(context.Background(), test.src)
+			got := fmt.Sprint(result)
+			if err != nil {
+				got = "error " + err.Error()
+			}
+			if got != test.want {
+				t.Errorf("engine %d: %q: got %s, want %s", engine, test.src, got, test.want)
+			}
+		}
+	}
+}
+
+func TestNativeFunctionErrors(t *testing.T) {
+	interpreter, err := NewInterpreter(Options{Globals: map[string]any{
+		"fail": func() error { return errors.New("broken") },
+		"half": func(n float64) float64 { return n / 2 },
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = interpreter.Eval(context.Background(), "fn run() { fail() }\nrun()")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err == nil || runtimeErr.Err.Error() != "fail: broken" || len(runtimeErr.Stack) != 2 {
+		t.Errorf("got %v, want the error of fail wrapped at its call in run", err)
+	}
+	if _, err := interpreter.Eval(context.Background(), `half("a")`); !errors.Is(err, TypeError) {
+		t.Errorf("got %v, want a type error for a string argument", err)
+	}
+
+	for _, fn := range []any{nil, 5, func() (int, int) { return 1, 2 }, func() (int, int, error) { return 1, 2, nil }} {
+		if _, err := NewNativeFunction("f", fn); err == nil {
+			t.Errorf("NewNativeFunction(%T): got no error", fn)
+		}
+		if err := interpreter.RegisterFunc("f", fn); err == nil {
+			t.Errorf("RegisterFunc(%T): got no error", fn)
+		}
+	}
+}
+
+func TestNativeFunctionCall(t *testing.T) {
+	fn, err := NewNativeFunction("repeat", strings.Repeat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.Name() != "repeat" || fn.String() != "fn repeat(string, int) string" {
+		t.Errorf("got %s named %s", fn, fn.Name())
+	}
+	if result, err := fn.Call([]any{"ab", int64(3)}); err != nil || result != "ababab" {
+		t.Errorf("got %v, %v, want ababab", result, err)
+	}
+	if _, err := fn.Call([]any{int64(3), "ab"}); err == nil {
+		t.Errorf("got no error for arguments of the wrong types")
+	}
+}
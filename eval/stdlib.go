@@ -0,0 +1,349 @@
+package eval
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"myProgrammingLanguage/parse"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// builtin is a function of the standard library. Fn is a Go function wrapped like NewNativeFunction
+// does or a builtinFunc, signature is the palm signature shown in the reference.
+type builtin struct {
+	name      string
+	signature string
+	doc       string
+	fn        any
+}
+
+// builtinModule is a module of the standard library, the functions of the module without a name are
+// global functions
+type builtinModule struct {
+	name  string
+	doc   string
+	funcs []builtin
+}
+
+// stdlib is the standard library, the check package declares the same functions for the checker
+var stdlib = []builtinModule{
+	{
+		doc: "The builtin functions are defined in every program and module.",
+		funcs: []builtin{
+			{"print", "(interface... values)", "print writes the values to the standard output separated by spaces. " +
+				"Strings are written as they are, other values like the repl shows them.", builtinFunc(printValues)},
+			{"println", "(interface... values)", "println writes the values like print followed by a newline.", builtinFunc(printlnValues)},
+			{"len", "(interface value) int", "len returns the number of elements of an array or the number of characters " +
+				"of a string.", builtinFunc(length)},
+			{"assert", "(bool cond, string msg)", "assert stops the program with an assertion failure at the call if cond " +
+				"is false, the error shows msg.", builtinFunc(assert)},
+			{"assertEq", "(interface a, interface b)", "assertEq stops the program with an assertion failure at the call if " +
+				"a and b are not equal. Numbers are compared by value, arrays and structs element by element.", builtinFunc(assertEq)},
+		},
+	},
+	{
+		name: "math",
+		doc:  "The math module holds mathematical functions, int arguments are converted to float.",
+		funcs: []builtin{
+			{"abs", "(float x) float", "abs returns the absolute value of x.", math.Abs},
+			{"min", "(float x, float y) float", "min returns the smaller of x and y.", math.Min},
+			{"max", "(float x, float y) float", "max returns the larger of x and y.", math.Max},
+			{"pow", "(float x, float y) float", "pow returns x to the power of y.", math.Pow},
+			{"sqrt", "(float x) float", "sqrt returns the square root of x.", math.Sqrt},
+		},
+	},
+	{
+		name: "strings",
+		doc:  "The strings module holds functions working with strings.",
+		funcs: []builtin{
+			{"split", "(string s, string sep) string[]", "split returns the parts of s between the separators sep, " +
+				"an empty sep splits s into its characters.", strings.Split},
+			{"join", "(string[] parts, string sep) string", "join concatenates the parts with sep between them.", strings.Join},
+			{"contains", "(string s, string substr) bool", "contains reports whether substr is in s.", strings.Contains},
+			{"upper", "(string s) string", "upper returns s with all letters in upper case.", strings.ToUpper},
+			{"lower", "(string s) string", "lower returns s with all letters in lower case.", strings.ToLower},
+		},
+	},
+	{
+		name: "io",
+		doc:  "The io module reads the standard input and reads and writes files.",
+		funcs: []builtin{
+			{"readLine", "() string", "readLine returns the next line of the standard input without its line break, " +
+				"it fails at the end of the input.", readLine},
+			{"readFile", "(string path) string", "readFile returns the content of the file at path, files larger than " +
+				"the maximum collection size are not read.", builtinFunc(readFile)},
+			{"writeFile", "(string path, string content)", "writeFile writes the content to the file at path, " +
+				"an existing file is replaced.", writeFile},
+		},
+	},
+	{
+		name: "time",
+		doc:  "The time module reads the clock and pauses the program.",
+		funcs: []builtin{
+			{"now", "() int", "now returns the current time in milliseconds since January 1, 1970 UTC.", now},
+			{"sleep", "(int ms)", "sleep pauses the program for ms milliseconds, it stops early when the evaluation " +
+				"is canceled or its time limit is exceeded.", builtinFunc(sleep)},
+		},
+	},
+}
+
+// ioNames are the builtins and modules using files or the standard streams, sandboxed interpreters
+// don't have them
+var ioNames = map[string]bool{"print": true, "println": true, "io": true}
+
+var (
+	// universe is the outer scope of the global scopes, it holds the builtins and the modules of the standard
+	// library. Programs may declare globals with the same names, but never change the universe.
+	universe = newUniverse(true)
+	// sandbox is the universe of interpreters which don't allow io, see Options.AllowIO
+	sandbox = newUniverse(false)
+)
+
+// newUniverse returns a scope with the standard library, the builtins in ioNames are left out without io
+func newUniverse(io bool) *parse.Scope {
+	scope := parse.NewScope(nil)
+	for _, mod := range stdlib {
+		if !io && ioNames[mod.name] {
+			continue
+		}
+		target := scope
+		if mod.name != "" {
+			target = parse.NewScope(nil)
+			scope.Define(mod.name, &Module{Name: mod.name, Scope: target})
+		}
+		for _, fn := range mod.funcs {
+			if !io && mod.name == "" && ioNames[fn.name] {
+				continue
+			}
+			target.Define(fn.name, mod.native(fn))
+		}
+	}
+	return scope
+}
+
+// native returns the native function of the builtin, functions of modules are named like module.name
+func (m builtinModule) native(fn builtin) *NativeFunction {
+	name := fn.name
+	if m.name != "" {
+		name = m.name + "." + fn.name
+	}
+	if f, ok := fn.fn.(builtinFunc); ok {
+		return newBuiltin(name, fn.signature, f)
+	}
+	native, err := NewNativeFunction(name, fn.fn)
+	if err != nil {
+		panic(err)
+	}
+	return native
+}
+
+// NewGlobalScope returns an empty scope for the global variables of a program, the standard library is
+// defined in its outer scope
+func NewGlobalScope() *parse.Scope {
+	return parse.NewScope(universe)
+}
+
+// assignsBuiltin reports whether assigning the variable in the scope would change the universe, that is
+// if the variable is a builtin which isn't shadowed by a variable of the program
+func assignsBuiltin(scope *parse.Scope, name string) bool {
+	for ; scope != nil; scope = scope.Parent() {
+		if _, ok := scope.ResolveLocal(name); ok {
+			return scope == universe || scope == sandbox
+		}
+	}
+	return false
+}
+
+// WriteReference writes the reference of the standard library as markdown
+func WriteReference(w io.Writer) error {
+	builder := strings.Builder{}
+	builder.WriteString("# Standard library\n\nThis file is generated by `palm doc`, do not edit it.\n")
+	for _, mod := range stdlib {
+		title := "Builtins"
+		if mod.name != "" {
+			title = "Module " + mod.name
+		}
+		fmt.Fprintf(&builder, "\n## %s\n\n%s\n", title, mod.doc)
+		for _, fn := range mod.funcs {
+			name := mod.native(fn).Name()
+			fmt.Fprintf(&builder, "\n### %s\n\n```palm\nfn %s%s\n```\n\n%s\n", name, name, fn.signature, fn.doc)
+		}
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// checkArgs checks the number and the types of the arguments of a builtin
+func checkArgs(name string, args []any, types ...*Type) error {
+	if len(args) != len(types) {
+		return fmt.Errorf("%s expects %d arguments, got %d", name, len(types), len(args))
+	}
+	for i, arg := range args {
+		if _, ok := convertTo(types[i], arg); !ok {
+			return newNativeError(TypeError, "cannot use %s value as %s in argument %d of %s", typeOf(arg), types[i], i+1, name)
+		}
+	}
+	return nil
+}
+
+func printValues(ctx context.Context, args []any) (any, error) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			values[i] = s
+		} else {
+			values[i] = fmt.Sprint(arg)
+		}
+	}
+	_, err := io.WriteString(os.Stdout, strings.Join(values, " "))
+	return nil, err
+}
+
+func printlnValues(ctx context.Context, args []any) (any, error) {
+	if _, err := printValues(ctx, args); err != nil {
+		return nil, err
+	}
+	_, err := io.WriteString(os.Stdout, "\n")
+	return nil, err
+}
+
+func length(ctx context.Context, args []any) (any, error) {
+	if err := checkArgs("len", args, interfaceType); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case *Array:
+		return int64(len(v.Elements)), nil
+	case string:
+		return int64(utf8.RuneCountInString(v)), nil
+	}
+	return nil, newNativeError(TypeError, "cannot use %s value as string or array in argument 1 of len", typeOf(args[0]))
+}
+
+func assert(ctx context.Context, args []any) (any, error) {
+	if err := checkArgs("assert", args, boolType, stringType); err != nil {
+		return nil, err
+	}
+	if !args[0].(bool) {
+		return nil, newNativeError(AssertionFailed, "assertion failed: %s", args[1])
+	}
+	return nil, nil
+}
+
+func assertEq(ctx context.Context, args []any) (any, error) {
+	if err := checkArgs("assertEq", args, interfaceType, interfaceType); err != nil {
+		return nil, err
+	}
+	if !valuesEqual(args[0], args[1]) {
+		return nil, newNativeError(AssertionFailed, "assertion failed: %s != %s", formatValue(args[0]), formatValue(args[1]))
+	}
+	return nil, nil
+}
+
+// valuesEqual reports whether the values are deeply equal, ints and floats are compared as numbers
+func valuesEqual(a, b any) bool {
+	switch a := a.(type) {
+	case int64:
+		if f, ok := b.(float64); ok {
+			return float64(a) == f
+		}
+	case float64:
+		if i, ok := b.(int64); ok {
+			return a == float64(i)
+		}
+	case *Array:
+		other, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !valuesEqual(a.Elements[i], other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		other, ok := b.(*Struct)
+		if !ok || a == nil || other == nil || a.Type != other.Type {
+			return a == other
+		}
+		for i := range a.Fields {
+			if !valuesEqual(a.Fields[i], other.Fields[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// stdin reads the standard input for readLine, it is created by the first call
+var stdin *bufio.Reader
+
+func readLine() (string, error) {
+	if stdin == nil {
+		stdin = bufio.NewReader(os.Stdin)
+	}
+	line, err := stdin.ReadString('\n')
+	if errors.Is(err, io.EOF) && line != "" {
+		err = nil
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", errors.New("end of input")
+		}
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+func readFile(ctx context.Context, args []any) (any, error) {
+	if err := checkArgs("io.readFile", args, stringType); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(args[0].(string))
+	if err != nil {
+		return nil, fmt.Errorf("io.readFile: %w", err)
+	}
+	defer f.Close()
+
+	// one byte more than the limit is enough for the engine to report the size
+	var r io.Reader = f
+	if max := maxCollectionSize(ctx); max > 0 {
+		r = io.LimitReader(f, int64(max)+1)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("io.readFile: %w", err)
+	}
+	return string(content), nil
+}
+
+func writeFile(path, content string) error {
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+func now() int64 {
+	return time.Now().UnixMilli()
+}
+
+func sleep(ctx context.Context, args []any) (any, error) {
+	if err := checkArgs("time.sleep", args, intType); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(time.Duration(args[0].(int64)) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
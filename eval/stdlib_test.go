@@ -0,0 +1,63 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSandboxHasNoIO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+		interpreter, err := NewInterpreter(Options{Engine: engine})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, src := range []string{`io.writeFile("` + path + `", "x")`, `io.readFile("/etc/hostname")`, `print("x")`, `println("x")`} {
+			_, err := interpreter.Eval(context.Background(), src)
+			if err == nil || !strings.Contains(err.Error(), "undefined variable") {
+				t.Errorf("engine %d: %s: got %v, want undefined variable", engine, src, err)
+			}
+		}
+		// the other modules are still defined
+		if result, err := interpreter.Eval(context.Background(), "math.max(1, 2)"); err != nil || result != 2.0 {
+			t.Errorf("engine %d: math.max: got %v, %v", engine, result, err)
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Errorf("sandboxed script wrote %s", path)
+	}
+}
+
+func TestAllowIO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	interpreter, err := NewInterpreter(Options{AllowIO: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := interpreter.Eval(context.Background(), `io.writeFile("`+path+`", "hello")
+io.readFile("`+path+`")`)
+	if err != nil || result != "hello" {
+		t.Errorf("got %v, %v, want hello", result, err)
+	}
+}
+
+func TestReadFileSizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 1000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, engine := range []Engine{TreeWalker, BytecodeVM} {
+		interpreter, err := NewInterpreter(Options{Engine: engine, AllowIO: true, Limits: Limits{MaxCollectionSize: 10}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = interpreter.Eval(context.Background(), `io.readFile("`+path+`")`)
+		if !errors.Is(err, LimitExceeded) || !strings.Contains(err.Error(), "string of 11 bytes") {
+			t.Errorf("engine %d: got %v, want a limit error for 11 bytes", engine, err)
+		}
+	}
+}
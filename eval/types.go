@@ -0,0 +1,410 @@
+package eval
+
+import (
+	"fmt"
+	"myProgrammingLanguage/parse"
+	"strconv"
+	"strings"
+)
+
+type TypeKind int
+
+const (
+	TypeInterface TypeKind = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeString
+	TypeArray
+	TypeFunction
+	TypeStruct
+	TypeModule
+)
+
+// Type describes the type of palm runtime value.
+// Struct types are compared by identity, so aliases of the same struct declaration are equal.
+type Type struct {
+	Kind   TypeKind
+	Elem   *Type
+	Name   string
+	Fields []StructField
+}
+
+type StructField struct {
+	Name string
+	Type *Type
+}
+
+// FieldIndex returns the index of the field with the given name or -1 if there is no such field
+func (t *Type) FieldIndex(name string) int {
+	for i, field := range t.Fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	interfaceType = &Type{Kind: TypeInterface}
+	intType       = &Type{Kind: TypeInt}
+	floatType     = &Type{Kind: TypeFloat}
+	boolType      = &Type{Kind: TypeBool}
+	stringType    = &Type{Kind: TypeString}
+	functionType  = &Type{Kind: TypeFunction}
+	moduleType    = &Type{Kind: TypeModule}
+)
+
+var builtinTypes = map[string]*Type{
+	"interface": interfaceType,
+	"int":       intType,
+	"float":     floatType,
+	"bool":      boolType,
+	"string":    stringType,
+	"fn":        functionType,
+}
+
+func arrayOf(elem *Type) *Type {
+	return &Type{Kind: TypeArray, Elem: elem}
+}
+
+// typeScope is where an engine looks up and defines the types declared by the program, the scopes of the
+// tree walker or the compile time scopes of the compiler
+type typeScope interface {
+	lookupType(name string) (*Type, bool)
+	defineType(name string, typ *Type)
+	errorAt(loc parse.TokenLocation, format string, args ...any) error
+}
+
+// resolveType converts a type annotation to the runtime type
+func resolveType(scope typeScope, node *parse.TypeNode) (*Type, error) {
+	if node.IsArray() {
+		elem, err := resolveType(scope, node.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return arrayOf(elem), nil
+	}
+
+	if node.IsStruct() {
+		typ := &Type{Kind: TypeStruct, Name: node.String()}
+		return typ, resolveFields(scope, typ, node)
+	}
+
+	if typ, ok := builtinTypes[node.Name.Val]; ok {
+		return typ, nil
+	}
+
+	typ, ok := scope.lookupType(node.Name.Val)
+	if !ok {
+		return nil, scope.errorAt(node.Name.Loc, "unknown type %s", node.Name.Val)
+	}
+	return typ, nil
+}
+
+func resolveFields(scope typeScope, typ *Type, node *parse.TypeNode) error {
+	for _, field := range node.Fields {
+		if typ.FieldIndex(field.Name.Val) >= 0 {
+			return scope.errorAt(field.Name.Loc, "duplicate field %s", field.Name.Val)
+		}
+
+		fieldType, err := resolveType(scope, field.Type)
+		if err != nil {
+			return err
+		}
+		typ.Fields = append(typ.Fields, StructField{Name: field.Name.Val, Type: fieldType})
+	}
+	return nil
+}
+
+// resolveTypeDeclaration defines the type of the declaration in the scope and returns it, the
+// engines check that the name isn't defined yet
+func resolveTypeDeclaration(scope typeScope, node *parse.TypeDeclarationNode) (*Type, error) {
+	if node.Type.IsStruct() {
+		// struct is defined before its fields are resolved, so that it can refer to itself
+		typ := &Type{Kind: TypeStruct, Name: node.Name.Val}
+		scope.defineType(node.Name.Val, typ)
+		return typ, resolveFields(scope, typ, node.Type)
+	}
+
+	typ, err := resolveType(scope, node.Type)
+	if err != nil {
+		return nil, err
+	}
+	scope.defineType(node.Name.Val, typ)
+	return typ, nil
+}
+
+func (t *Type) String() string {
+	switch t.Kind {
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	case TypeString:
+		return "string"
+	case TypeArray:
+		return t.Elem.String() + "[]"
+	case TypeFunction:
+		return "fn"
+	case TypeStruct:
+		return t.Name
+	case TypeModule:
+		return "module"
+	}
+	return "interface"
+}
+
+func (t *Type) Equal(other *Type) bool {
+	if t.Kind != other.Kind {
+		return false
+	}
+	if t.Kind == TypeArray {
+		return t.Elem.Equal(other.Elem)
+	}
+	if t.Kind == TypeStruct {
+		return t == other
+	}
+	return true
+}
+
+// zeroValue returns the value of a struct field which is not given in the struct literal
+func zeroValue(t *Type) interface{} {
+	return zeroValueOf(t, map[*Type]bool{})
+}
+
+func zeroValueOf(t *Type, visiting map[*Type]bool) interface{} {
+	switch t.Kind {
+	case TypeInt:
+		return int64(0)
+	case TypeFloat:
+		return float64(0)
+	case TypeBool:
+		return false
+	case TypeString:
+		return ""
+	case TypeArray:
+		return &Array{Elem: t.Elem, Elements: []interface{}{}}
+	case TypeStruct:
+		// self referencing structs would never end
+		if visiting[t] {
+			return nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Struct{Type: t, Fields: make([]interface{}, len(t.Fields))}
+		for i, field := range t.Fields {
+			s.Fields[i] = zeroValueOf(field.Type, visiting)
+		}
+		return s
+	}
+	return nil
+}
+
+// typeOf returns the dynamic type of given runtime value
+func typeOf(val interface{}) *Type {
+	switch v := val.(type) {
+	case int64:
+		return intType
+	case float64:
+		return floatType
+	case bool:
+		return boolType
+	case string:
+		return stringType
+	case *Array:
+		return arrayOf(v.Elem)
+	case *Function, *NativeFunction, *Closure:
+		return functionType
+	case *Struct:
+		return v.Type
+	case *Module:
+		return moduleType
+	}
+	return interfaceType
+}
+
+// convertTo checks whether val can be stored in a variable of type t and returns the converted value.
+// Ints are promoted to floats, arrays are copied if their element type differs from t.
+func convertTo(t *Type, val interface{}) (interface{}, bool) {
+	switch t.Kind {
+	case TypeInterface:
+		return val, true
+	case TypeInt:
+		_, ok := val.(int64)
+		return val, ok
+	case TypeFloat:
+		if i, ok := val.(int64); ok {
+			return float64(i), true
+		}
+		_, ok := val.(float64)
+		return val, ok
+	case TypeBool:
+		_, ok := val.(bool)
+		return val, ok
+	case TypeString:
+		_, ok := val.(string)
+		return val, ok
+	case TypeFunction:
+		switch val.(type) {
+		case *Function, *NativeFunction, *Closure:
+			return val, true
+		}
+		return nil, false
+	case TypeStruct:
+		s, ok := val.(*Struct)
+		return val, ok && s.Type == t
+	case TypeArray:
+		arr, ok := val.(*Array)
+		if !ok {
+			return nil, false
+		}
+		if arr.Elem.Equal(t.Elem) {
+			return arr, true
+		}
+		converted := &Array{Elem: t.Elem, Elements: make([]interface{}, len(arr.Elements))}
+		for i, element := range arr.Elements {
+			convertedElement, ok := convertTo(t.Elem, element)
+			if !ok {
+				return nil, false
+			}
+			converted.Elements[i] = convertedElement
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+// assignedValue converts a value assigned to a variable. Variables declared with a type keep it,
+// declared is nil for the others and their float values, or arrays of them, stay float like the checker
+// infers them. ok is false if the value doesn't convert to the declared type.
+func assignedValue(declared *Type, current, val interface{}) (interface{}, bool) {
+	if declared != nil {
+		return convertTo(declared, val)
+	}
+	if t := typeOf(current); holdsFloats(t) {
+		if converted, ok := convertTo(t, val); ok {
+			return converted, true
+		}
+	}
+	return val, true
+}
+
+// holdsFloats reports whether the type is float or an array of them
+func holdsFloats(t *Type) bool {
+	for t.Kind == TypeArray {
+		t = t.Elem
+	}
+	return t.Kind == TypeFloat
+}
+
+// Array is the runtime value of palm arrays, arrays are passed by reference
+type Array struct {
+	Elem     *Type
+	Elements []interface{}
+}
+
+// newArray creates an array by inferring the element type from the given elements.
+// Mixed int and float elements make a float array, other mixtures make an interface array.
+func newArray(elements []interface{}) *Array {
+	if len(elements) == 0 {
+		return &Array{Elem: interfaceType, Elements: elements}
+	}
+
+	elem := typeOf(elements[0])
+	for _, element := range elements[1:] {
+		elemType := typeOf(element)
+		if elem.Equal(elemType) {
+			continue
+		}
+		if (elem.Kind == TypeInt || elem.Kind == TypeFloat) && (elemType.Kind == TypeInt || elemType.Kind == TypeFloat) {
+			elem = floatType
+			continue
+		}
+		elem = interfaceType
+		break
+	}
+
+	arr := &Array{Elem: elem, Elements: make([]interface{}, len(elements))}
+	for i, element := range elements {
+		arr.Elements[i], _ = convertTo(elem, element)
+	}
+	return arr
+}
+
+func (a *Array) String() string {
+	builder := strings.Builder{}
+	builder.WriteString("[")
+	for i, element := range a.Elements {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(formatValue(element))
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+// Struct is the runtime value of palm structs, structs are passed by reference like arrays
+type Struct struct {
+	Type   *Type
+	Fields []interface{}
+}
+
+func (s *Struct) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(s.Type.Name)
+	builder.WriteString("{")
+	for i, field := range s.Type.Fields {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(field.Name)
+		builder.WriteString(": ")
+		builder.WriteString(formatValue(s.Fields[i]))
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+// formatValue formats values nested in arrays and structs, strings are quoted
+func formatValue(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(val)
+}
+
+// Function is the runtime value of palm functions.
+// Scope is the scope the function is defined in, so functions close over it.
+type Function struct {
+	Node       *parse.FunctionNode
+	Scope      *parse.Scope
+	Params     []*Type
+	ReturnType *Type
+}
+
+// Module is the runtime value of an imported module, Scope is the global scope the module is evaluated in
+type Module struct {
+	Name  string
+	Path  string
+	Scope *parse.Scope
+}
+
+func (m *Module) String() string {
+	return "module " + m.Name
+}
+
+func (f *Function) Name() string {
+	if f.Node.Name.Val == "" {
+		return "<anonymous>"
+	}
+	return f.Node.Name.Val
+}
+
+func (f *Function) String() string {
+	return f.Node.Signature()
+}
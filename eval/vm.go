@@ -0,0 +1,469 @@
+package eval
+
+import (
+	"context"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+)
+
+// VM is a stack machine running protos created by Compile.
+// Globals live in a parse.Scope like for the tree walker, locals in the slots of environments.
+type VM struct {
+	callStack
+	globals *parse.Scope
+	ctx     context.Context
+	imports *Importer
+	stack   []interface{}
+	frames  []vmFrame
+}
+
+// vmFrame is an active call of a proto, base is the stack index of the callee
+type vmFrame struct {
+	proto   *Proto
+	pc      int
+	base    int
+	env     *env
+	globals *parse.Scope
+	loc     parse.TokenLocation
+}
+
+// location returns the location of the next instruction, instructions compiled without a token like the pops
+// between statements report the location of the closest instruction before them
+func (f *vmFrame) location() parse.TokenLocation {
+	for pc := f.pc; pc >= 0; pc-- {
+		if loc := f.proto.Tokens[pc].Loc; loc.Start.Filename != "" {
+			return loc
+		}
+	}
+	return f.loc
+}
+
+func NewVM(globals *parse.Scope) *VM {
+	return &VM{globals: globals, ctx: context.Background()}
+}
+
+// SetLimits bounds the work of Run, see Limits
+func (vm *VM) SetLimits(limits Limits) {
+	vm.limits = limits
+}
+
+// Run executes the compiled program and returns the value of its last statement.
+// Go panics are recovered and returned as InternalError like Evaluate does.
+func (vm *VM) Run(ctx context.Context, proto *Proto) (interface{}, error) {
+	vm.steps = 0
+	return vm.execute(ctx, proto)
+}
+
+// execute runs the program like Run, counting steps from the steps already taken
+func (vm *VM) execute(ctx context.Context, proto *Proto) (result interface{}, err error) {
+	vm.ctx = ctx
+	vm.stack = vm.stack[:0]
+	vm.frames = append(vm.frames[:0], vmFrame{proto: proto, globals: vm.globals})
+	vm.calls = nil
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, vm.recovered(r)
+		}
+	}()
+	return vm.run()
+}
+
+func (vm *VM) push(val interface{}) {
+	vm.stack = append(vm.stack, val)
+}
+
+func (vm *VM) pop() interface{} {
+	val := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return val
+}
+
+func (vm *VM) top() interface{} {
+	return vm.stack[len(vm.stack)-1]
+}
+
+func (vm *VM) run() (interface{}, error) {
+	frame := &vm.frames[len(vm.frames)-1]
+
+	for {
+		if err := vm.step(vm.ctx, func() parse.TokenLocation { return frame.location() }); err != nil {
+			return nil, err
+		}
+		ins := frame.proto.Code[frame.pc]
+		frame.pc++
+
+		switch ins.Op {
+		case OpConstant:
+			vm.push(frame.proto.Constants[ins.A])
+		case OpNil:
+			vm.push(nil)
+		case OpPop:
+			vm.pop()
+		case OpDup:
+			vm.push(vm.top())
+		case OpDup2:
+			n := len(vm.stack)
+			vm.stack = append(vm.stack, vm.stack[n-2], vm.stack[n-1])
+		case OpGetLocal:
+			e := frame.env
+			for depth := ins.A; depth > 0; depth-- {
+				e = e.parent
+			}
+			vm.push(e.slots[ins.B])
+		case OpSetLocal:
+			e := frame.env
+			for depth := ins.A; depth > 0; depth-- {
+				e = e.parent
+			}
+			e.slots[ins.B] = vm.top()
+		case OpAssignLocal:
+			e := frame.env
+			for depth := ins.A; depth > 0; depth-- {
+				e = e.parent
+			}
+			val, _ := assignedValue(nil, e.slots[ins.B], vm.top())
+			e.slots[ins.B] = val
+			vm.stack[len(vm.stack)-1] = val
+		case OpGetGlobal, OpGetDefinedGlobal:
+			name := frame.proto.Constants[ins.A].(string)
+			val, ok := frame.globals.Resolve(name)
+			if !ok {
+				loc := frame.proto.Tokens[frame.pc-1].Loc
+				if ins.Op == OpGetDefinedGlobal {
+					return nil, vm.errorAt(loc, "variable %s not defined", name)
+				}
+				return nil, vm.errorAt(loc, "undefined variable %s", name)
+			}
+			vm.push(val)
+		case OpGetVar:
+			ref := frame.proto.Constants[ins.A].(*varRef)
+			if e, local := ref.find(frame.env); e != nil {
+				vm.push(e.slots[local.slot])
+				break
+			}
+			val, ok := frame.globals.Resolve(ref.name)
+			if !ok {
+				loc := frame.proto.Tokens[frame.pc-1].Loc
+				if ins.B == 1 {
+					return nil, vm.errorAt(loc, "variable %s not defined", ref.name)
+				}
+				return nil, vm.errorAt(loc, "undefined variable %s", ref.name)
+			}
+			vm.push(val)
+		case OpSetVar:
+			ref := frame.proto.Constants[ins.A].(*varRef)
+			token := frame.proto.Tokens[frame.pc-1]
+			e, local := ref.find(frame.env)
+			if e == nil {
+				val, err := vm.assignVariable(frame.globals, token, vm.top(), false)
+				if err != nil {
+					return nil, err
+				}
+				vm.stack[len(vm.stack)-1] = val
+				break
+			}
+			val, ok := assignedValue(local.declared, e.slots[local.slot], vm.top())
+			if !ok {
+				return nil, vm.typeErrorAt(token.Loc, "cannot use %s value as %s in assignment to %s", typeOf(vm.top()), local.declared, ref.name)
+			}
+			e.slots[local.slot] = val
+			vm.stack[len(vm.stack)-1] = val
+		case OpSetGlobal:
+			val, err := vm.assignVariable(frame.globals, frame.proto.Tokens[frame.pc-1], vm.top(), ins.B == 0)
+			if err != nil {
+				return nil, err
+			}
+			vm.stack[len(vm.stack)-1] = val
+		case OpDefineGlobal, OpDefineTyped:
+			name := frame.proto.Constants[ins.A].(string)
+			if _, ok := frame.globals.ResolveLocal(name); ok {
+				what := "variable"
+				if ins.Op == OpDefineGlobal && ins.B == 1 {
+					what = "function"
+				}
+				return nil, vm.errorAt(frame.proto.Tokens[frame.pc-1].Loc, "%s %s already defined", what, name)
+			}
+			if ins.Op == OpDefineTyped {
+				frame.globals.DefineTyped(name, vm.top(), frame.proto.Constants[ins.B])
+			} else {
+				frame.globals.Define(name, vm.top())
+			}
+		case OpDefineType:
+			frame.globals.DefineType(frame.proto.Constants[ins.B].(string), frame.proto.Constants[ins.A])
+		case OpDeclareType:
+			typ := frame.proto.Constants[ins.A].(*Type)
+			converted, ok := convertTo(typ, vm.top())
+			if !ok {
+				token := frame.proto.Tokens[frame.pc-1]
+				what := "declaration of"
+				if ins.B == 1 {
+					what = "assignment to"
+				}
+				return nil, vm.typeErrorAt(token.Loc, "cannot use %s value as %s in %s %s", typeOf(vm.top()), typ, what, token.Val)
+			}
+			vm.stack[len(vm.stack)-1] = converted
+		case OpArray:
+			start := len(vm.stack) - int(ins.A)
+			elements := make([]interface{}, ins.A)
+			copy(elements, vm.stack[start:])
+			vm.stack = vm.stack[:start]
+			arr := newArray(elements)
+			if err := vm.checkValueSize(frame.proto.Tokens[frame.pc-1].Loc, arr); err != nil {
+				return nil, err
+			}
+			vm.push(arr)
+		case OpStruct:
+			s, err := vm.newStruct(frame.proto.Constants[ins.A].(*structLayout))
+			if err != nil {
+				return nil, err
+			}
+			vm.push(s)
+		case OpIndex:
+			n := len(vm.stack)
+			arr, index, err := vm.indexArray(frame.proto.Tokens[frame.pc-1].Loc, vm.stack[n-2], vm.stack[n-1])
+			if err != nil {
+				return nil, err
+			}
+			vm.stack = vm.stack[:n-2]
+			vm.push(arr.Elements[index])
+		case OpCheckIndex:
+			n := len(vm.stack)
+			if _, _, err := vm.indexArray(frame.proto.Tokens[frame.pc-1].Loc, vm.stack[n-2], vm.stack[n-1]); err != nil {
+				return nil, err
+			}
+		case OpSetIndex:
+			n := len(vm.stack)
+			token := frame.proto.Tokens[frame.pc-1]
+			arr, index, err := vm.indexArray(token.Loc, vm.stack[n-3], vm.stack[n-2])
+			if err != nil {
+				return nil, err
+			}
+			val := vm.stack[n-1]
+			converted, ok := convertTo(arr.Elem, val)
+			if !ok {
+				return nil, vm.typeErrorAt(token.Loc, "cannot use %s value as %s in array assignment", typeOf(val), arr.Elem)
+			}
+			arr.Elements[index] = converted
+			vm.stack = vm.stack[:n-3]
+			vm.push(converted)
+		case OpField:
+			field := frame.proto.Tokens[frame.pc-1]
+			if mod, ok := vm.top().(*Module); ok {
+				val, err := vm.moduleMember(field, mod)
+				if err != nil {
+					return nil, err
+				}
+				vm.stack[len(vm.stack)-1] = val
+				break
+			}
+			s, index, err := vm.structField(field, vm.top())
+			if err != nil {
+				return nil, err
+			}
+			vm.stack[len(vm.stack)-1] = s.Fields[index]
+		case OpCheckField:
+			if _, _, err := vm.structField(frame.proto.Tokens[frame.pc-1], vm.top()); err != nil {
+				return nil, err
+			}
+		case OpSetField:
+			n := len(vm.stack)
+			s := vm.stack[n-2].(*Struct)
+			val := vm.stack[n-1]
+			index := s.Type.FieldIndex(frame.proto.Constants[ins.A].(string))
+			field := s.Type.Fields[index]
+			converted, ok := convertTo(field.Type, val)
+			if !ok {
+				loc := frame.proto.Tokens[frame.pc-1].Loc
+				return nil, vm.typeErrorAt(loc, "cannot use %s value as %s in assignment to field %s", typeOf(val), field.Type, field.Name)
+			}
+			s.Fields[index] = converted
+			vm.stack = vm.stack[:n-2]
+			vm.push(converted)
+		case OpBinary:
+			n := len(vm.stack)
+			op := &frame.proto.Tokens[frame.pc-1]
+			result, ok := intOperator(op.Kind, vm.stack[n-2], vm.stack[n-1])
+			if !ok {
+				var err error
+				result, err = vm.applyBinaryOperator(*op, vm.stack[n-2], vm.stack[n-1])
+				if err != nil {
+					return nil, err
+				}
+			}
+			vm.stack = vm.stack[:n-1]
+			vm.stack[n-2] = result
+		case OpUnary:
+			result, err := vm.applyUnaryOperator(frame.proto.Tokens[frame.pc-1], vm.top())
+			if err != nil {
+				return nil, err
+			}
+			vm.stack[len(vm.stack)-1] = result
+		case OpJump:
+			frame.pc = int(ins.A)
+		case OpJumpIfFalse:
+			condition := vm.pop()
+			ok, isBool := condition.(bool)
+			if !isBool {
+				token := frame.proto.Tokens[frame.pc-1]
+				what := "if condition"
+				if token.Kind == parse.FOR {
+					what = "loop condition"
+				}
+				return nil, vm.typeErrorAt(token.Loc, "%s must be bool, got %s", what, typeOf(condition))
+			}
+			if !ok {
+				frame.pc = int(ins.A)
+			}
+		case OpPushEnv:
+			frame.env = &env{slots: make([]interface{}, ins.A), parent: frame.env}
+			if ins.B == 1 {
+				for i := range frame.env.slots {
+					frame.env.slots[i] = undeclaredSlot{}
+				}
+			}
+		case OpPopEnv:
+			frame.env = frame.env.parent
+		case OpClosure:
+			vm.push(&Closure{proto: frame.proto.Constants[ins.A].(*Proto), env: frame.env, globals: frame.globals})
+		case OpCall:
+			if err := vm.call(frame, ins); err != nil {
+				return nil, err
+			}
+			frame = &vm.frames[len(vm.frames)-1]
+		case OpImport:
+			if vm.imports == nil {
+				vm.imports = NewImporter(module.NewLoader(module.DefaultSearchPath()), BytecodeVM, vm.limits)
+			}
+			if err := vm.imports.importModule(vm.ctx, &vm.callStack, frame.proto.Constants[ins.A].(*parse.ImportNode), frame.globals); err != nil {
+				return nil, err
+			}
+			vm.push(nil)
+		case OpFail:
+			failure := frame.proto.Constants[ins.A].(*RuntimeError)
+			return nil, vm.newError(failure.Kind, failure.Loc, "%s", failure.Msg)
+		case OpReturn:
+			result := vm.pop()
+			if len(vm.frames) == 1 {
+				return result, nil
+			}
+
+			returned := vm.frames[len(vm.frames)-1]
+			vm.stack = vm.stack[:returned.base]
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.calls = vm.calls[:len(vm.calls)-1]
+			frame = &vm.frames[len(vm.frames)-1]
+
+			if typ := returned.proto.ReturnType; typ != nil {
+				converted, ok := convertTo(typ, result)
+				if !ok {
+					return nil, vm.typeErrorAt(returned.loc, "%s must return %s, got %s", returned.proto.Name, typ, typeOf(result))
+				}
+				result = converted
+			}
+			vm.push(result)
+		}
+	}
+}
+
+// intOperator is the fast path of binary operators on ints, ok is false if the operator
+// has to be applied by applyBinaryOperator
+func intOperator(kind parse.TokenKind, left, right interface{}) (interface{}, bool) {
+	l, ok := left.(int64)
+	if !ok {
+		return nil, false
+	}
+	r, ok := right.(int64)
+	if !ok {
+		return nil, false
+	}
+
+	switch kind {
+	case parse.PLUS:
+		return l + r, true
+	case parse.MINUS:
+		return l - r, true
+	case parse.MUL:
+		return l * r, true
+	case parse.LT:
+		return l < r, true
+	case parse.LTE:
+		return l <= r, true
+	case parse.GT:
+		return l > r, true
+	case parse.GTE:
+		return l >= r, true
+	case parse.EQ:
+		return l == r, true
+	case parse.NEQ:
+		return l != r, true
+	}
+	return nil, false
+}
+
+// call calls the function below the arguments on top of the stack. Closures get a new frame,
+// native functions are called right away.
+func (vm *VM) call(frame *vmFrame, ins Instruction) error {
+	argc := int(ins.A)
+	base := len(vm.stack) - argc - 1
+	loc := frame.proto.Tokens[frame.pc-1].Loc
+
+	switch fn := vm.stack[base].(type) {
+	case *Closure:
+		proto := fn.proto
+		if argc != len(proto.Params) {
+			return vm.errorAt(loc, "%s expects %d arguments, got %d", proto.Name, len(proto.Params), argc)
+		}
+
+		e := &env{slots: make([]interface{}, argc), parent: fn.env}
+		for i, arg := range vm.stack[base+1:] {
+			val, ok := convertTo(proto.Params[i], arg)
+			if !ok {
+				return vm.typeErrorAt(loc, "cannot use %s value as %s in argument %s of %s", typeOf(arg), proto.Params[i], proto.Node.Params[i].Name.Val, proto.Name)
+			}
+			e.slots[i] = val
+		}
+
+		if err := vm.checkDepth(loc); err != nil {
+			return err
+		}
+		vm.calls = append(vm.calls, call{function: proto.Name, loc: loc})
+		vm.frames = append(vm.frames, vmFrame{proto: proto, base: base, env: e, globals: fn.globals, loc: loc})
+		return nil
+	case *NativeFunction:
+		args := make([]interface{}, argc)
+		copy(args, vm.stack[base+1:])
+
+		result, err := vm.callNative(vm.ctx, loc, fn, args)
+		if err != nil {
+			return err
+		}
+		vm.stack = vm.stack[:base]
+		vm.push(result)
+		return nil
+	}
+
+	callee := frame.proto.Constants[ins.B]
+	return vm.typeErrorAt(loc, "cannot call non-function %s of type %s", callee, typeOf(vm.stack[base]))
+}
+
+// newStruct creates the struct of a struct literal from the field values on top of the stack
+func (vm *VM) newStruct(layout *structLayout) (*Struct, error) {
+	start := len(vm.stack) - len(layout.fields)
+	s := zeroValue(layout.typ).(*Struct)
+	for i, field := range layout.fields {
+		index := layout.typ.FieldIndex(field.Val)
+		if index < 0 {
+			return nil, vm.errorAt(field.Loc, "unknown field %s in struct literal of type %s", field.Val, layout.typ)
+		}
+
+		val := vm.stack[start+i]
+		converted, ok := convertTo(layout.typ.Fields[index].Type, val)
+		if !ok {
+			return nil, vm.typeErrorAt(field.Loc, "cannot use %s value as %s in field %s", typeOf(val), layout.typ.Fields[index].Type, field.Val)
+		}
+		s.Fields[index] = converted
+	}
+	vm.stack = vm.stack[:start]
+	return s, nil
+}
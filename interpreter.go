@@ -1,8 +0,0 @@
-package main
-
-import "myProgrammingLanguage/parse"
-
-type Interpreter struct {
-	scope *parse.Scope
-	tree  *parse.SyntaxTree
-}
@@ -0,0 +1,277 @@
+package lsp
+
+import (
+	"fmt"
+	"myProgrammingLanguage/check"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"net/url"
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open text document and the result of analyzing its text
+type document struct {
+	uri     string
+	version int
+	// path is the file of the document, imports are resolved relative to it
+	path string
+	text string
+	// lineStarts holds the offset of the first byte of every line
+	lineStarts []int
+
+	tree *parse.SyntaxTree
+	// syntaxErrors reports whether errors are syntax errors, type errors are only reported without them
+	syntaxErrors bool
+	errors       []parse.Err
+	checker      *check.Checker
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version, path: filePath(uri), text: text, lineStarts: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+	d.analyze()
+	return d
+}
+
+// filePath returns the path of a file uri, other uris are used as they are
+func filePath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// analyze parses and checks the text. The checker also runs on trees with syntax errors, so that hover
+// and definitions work while typing, but then its errors are not reported.
+func (d *document) analyze() {
+	parser := parse.NewParser(d.path, d.text)
+	d.tree, _ = parser.Parse()
+	d.errors = parser.Errors.GetErrors()
+	d.syntaxErrors = len(d.errors) > 0
+
+	d.checker = check.NewChecker(parse.NewErrorContainer())
+	d.checker.Loader = module.NewLoader(module.DefaultSearchPath())
+	d.checker.Check(d.tree)
+	if !d.syntaxErrors {
+		// the errors of imported modules are reported at their imports
+		d.errors = nil
+		for _, err := range d.checker.Errors.GetErrors() {
+			if err.File == d.path {
+				d.errors = append(d.errors, err)
+			}
+		}
+	}
+}
+
+// position converts a byte offset to a position
+func (d *document) position(offset int) Position {
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	start := d.lineStarts[line]
+	return Position{Line: line, Character: utf16Len(d.text[start:offset])}
+}
+
+// offset converts a position to a byte offset
+func (d *document) offset(pos Position) int {
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[pos.Line]
+	for units := 0; units < pos.Character && offset < len(d.text) && d.text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// spanRange returns the range of length bytes starting at the location
+func (d *document) spanRange(loc parse.TokenLocation, length int) Range {
+	return Range{Start: d.position(loc.Start.Offset), End: d.position(loc.Start.Offset + length)}
+}
+
+func (d *document) tokenRange(token parse.Token) Range {
+	return d.spanRange(token.Loc, token.Loc.End.Offset-token.Loc.Start.Offset)
+}
+
+// nodeRange returns the range from the first to the last token of the node
+func (d *document) nodeRange(node parse.Node) Range {
+	return Range{
+		Start: d.position(parse.FirstToken(node).Loc.Start.Offset),
+		End:   d.position(parse.LastToken(node).Loc.End.Offset),
+	}
+}
+
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, err := range d.errors {
+		diagnostic := Diagnostic{
+			Range:    d.spanRange(err.Loc, err.Len),
+			Severity: severity(err.Kind),
+			Source:   "palm",
+			Message:  err.Msg,
+		}
+		for _, label := range err.Labels {
+			diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, DiagnosticRelatedInformation{
+				Location: Location{URI: d.uri, Range: d.spanRange(label.Loc, label.Len)},
+				Message:  label.Msg,
+			})
+		}
+		for _, note := range err.Notes {
+			diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, DiagnosticRelatedInformation{
+				Location: Location{URI: d.uri, Range: d.spanRange(note.Loc, note.Len)},
+				Message:  note.Msg,
+			})
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+func severity(kind parse.ErrorKind) DiagnosticSeverity {
+	switch kind {
+	case parse.Warning:
+		return SeverityWarning
+	case parse.Note:
+		return SeverityInformation
+	}
+	return SeverityError
+}
+
+// identAt returns the identifier at the offset, the cursor may also be right after it
+func (d *document) identAt(offset int) (check.Ident, bool) {
+	for _, ident := range d.checker.Idents {
+		loc := ident.Token.Loc
+		if loc.Start.Offset <= offset && offset <= loc.End.Offset {
+			return ident, true
+		}
+	}
+	return check.Ident{}, false
+}
+
+func (d *document) hover(pos Position) *Hover {
+	ident, ok := d.identAt(d.offset(pos))
+	if !ok {
+		return nil
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: fmt.Sprintf("```palm\n%s %s\n```", ident.Token.Val, ident.Type)},
+		Range:    d.tokenRange(ident.Token),
+	}
+}
+
+func (d *document) definition(pos Position) *Location {
+	ident, ok := d.identAt(d.offset(pos))
+	// declarations in imported modules are not part of the document
+	if !ok || ident.Decl.Val == "" || ident.Decl.Loc.Start.Filename != d.path {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.tokenRange(ident.Decl)}
+}
+
+// symbols returns the declarations of the document, the declarations in functions are their children
+func (d *document) symbols() []DocumentSymbol {
+	if d.tree == nil || d.tree.Root == nil {
+		return []DocumentSymbol{}
+	}
+	return d.statementSymbols(d.tree.Root.(*parse.ProgramNode).Nodes)
+}
+
+func (d *document) statementSymbols(nodes []parse.Node) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *parse.FunctionNode:
+			if n.Name.Val == "" {
+				continue
+			}
+			symbols = append(symbols, DocumentSymbol{
+				Name:           n.Name.Val,
+				Detail:         n.Signature(),
+				Kind:           SymbolFunction,
+				Range:          d.nodeRange(n),
+				SelectionRange: d.tokenRange(n.Name),
+				Children:       d.statementSymbols(n.Body.Nodes),
+			})
+		case *parse.VariableDeclarationStatementNode:
+			symbol := DocumentSymbol{
+				Name:           n.Identifier.Val,
+				Kind:           SymbolVariable,
+				Range:          d.nodeRange(n),
+				SelectionRange: d.tokenRange(n.Identifier),
+			}
+			if typ, ok := d.checker.Types[n.Expression]; ok && !n.HasTypeToken {
+				symbol.Detail = typ.String()
+			} else if n.HasTypeToken {
+				symbol.Detail = n.Type.String()
+			}
+			symbols = append(symbols, symbol)
+		case *parse.TypeDeclarationNode:
+			symbol := DocumentSymbol{
+				Name:           n.Name.Val,
+				Kind:           SymbolTypeParam,
+				Range:          d.nodeRange(n),
+				SelectionRange: d.tokenRange(n.Name),
+			}
+			if n.Type.IsStruct() {
+				symbol.Kind = SymbolStruct
+				for _, field := range n.Type.Fields {
+					symbol.Children = append(symbol.Children, DocumentSymbol{
+						Name:           field.Name.Val,
+						Detail:         field.Type.String(),
+						Kind:           SymbolField,
+						Range:          Range{Start: d.position(parse.FirstToken(field.Type).Loc.Start.Offset), End: d.position(field.Name.Loc.End.Offset)},
+						SelectionRange: d.tokenRange(field.Name),
+					})
+				}
+			} else {
+				symbol.Detail = n.Type.String()
+			}
+			symbols = append(symbols, symbol)
+		case *parse.ImportNode:
+			symbols = append(symbols, DocumentSymbol{
+				Name:           n.Name(),
+				Detail:         n.Path.Val,
+				Kind:           SymbolModule,
+				Range:          d.nodeRange(n),
+				SelectionRange: d.tokenRange(n.Path.Token),
+			})
+		case *parse.BlockStatementNode:
+			symbols = append(symbols, d.statementSymbols(n.Nodes)...)
+		}
+	}
+	return symbols
+}
+
+// formatting returns the edit replacing the text with its formatted form, documents with syntax errors
+// are not formatted
+func (d *document) formatting() []TextEdit {
+	if d.syntaxErrors {
+		return nil
+	}
+	formatted := parse.Format(d.tree)
+	if formatted == d.text {
+		return []TextEdit{}
+	}
+	return []TextEdit{{
+		Range:   Range{Start: Position{}, End: d.position(len(d.text))},
+		NewText: formatted,
+	}}
+}
@@ -0,0 +1,206 @@
+// Package lsp implements a Language Server Protocol server for palm over standard input and output.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"myProgrammingLanguage/transport"
+)
+
+// Server serves one client, documents are synchronized fully on every change
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document
+	// shutdown is set by the shutdown request, the client may only exit after it
+	shutdown bool
+	// Log receives the messages of the server, it is io.Discard by default
+	Log io.Writer
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+		Log:  io.Discard,
+	}
+}
+
+// Serve handles messages until the exit notification or the end of the input. It returns an error if the
+// client exits without shutting the server down first.
+func (s *Server) Serve() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err != nil {
+			var rpcErr *responseError
+			if errors.As(err, &rpcErr) {
+				s.send(response(nil, nil, rpcErr))
+				continue
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				if !s.shutdown {
+					return errors.New("input closed before shutdown")
+				}
+				return nil
+			}
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+		s.handle(msg)
+	}
+}
+
+func (s *Server) handle(msg *message) {
+	result, err := s.dispatch(msg)
+	// notifications are never answered
+	if msg.ID == nil {
+		if err != nil {
+			fmt.Fprintf(s.Log, "%s: %s\n", msg.Method, err.Message)
+		}
+		return
+	}
+	s.send(response(msg.ID, result, err))
+}
+
+func (s *Server) send(v any) {
+	if err := transport.WriteMessage(s.out, v); err != nil {
+		fmt.Fprintln(s.Log, err)
+	}
+}
+
+// dispatch runs the handler of the method and returns its result
+func (s *Server) dispatch(msg *message) (any, *responseError) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:           1,
+				HoverProvider:              true,
+				DefinitionProvider:         true,
+				DocumentSymbolProvider:     true,
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "palm"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		item := params.TextDocument
+		s.open(newDocument(item.URI, item.Version, item.Text))
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		s.open(newDocument(params.TextDocument.URI, params.TextDocument.Version, text))
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		// the diagnostics of closed documents are cleared
+		s.send(notification("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		}))
+		return nil, nil
+
+	case "textDocument/hover":
+		doc, params, err := s.positionParams(msg)
+		if err != nil {
+			return nil, err
+		}
+		return doc.hover(params.Position), nil
+	case "textDocument/definition":
+		doc, params, err := s.positionParams(msg)
+		if err != nil {
+			return nil, err
+		}
+		return doc.definition(params.Position), nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.symbols(), nil
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err := unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.formatting(), nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+// open stores the document and publishes its diagnostics
+func (s *Server) open(doc *document) {
+	s.docs[doc.uri] = doc
+	s.send(notification("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         doc.uri,
+		Version:     doc.version,
+		Diagnostics: doc.diagnostics(),
+	}))
+}
+
+func (s *Server) document(uri string) (*document, *responseError) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %s is not open", uri)}
+	}
+	return doc, nil
+}
+
+func (s *Server) positionParams(msg *message) (*document, TextDocumentPositionParams, *responseError) {
+	var params TextDocumentPositionParams
+	if err := unmarshal(msg.Params, &params); err != nil {
+		return nil, params, err
+	}
+	doc, err := s.document(params.TextDocument.URI)
+	return doc, params, err
+}
+
+func unmarshal(params json.RawMessage, v any) *responseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
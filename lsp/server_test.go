@@ -0,0 +1,186 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"myProgrammingLanguage/transport"
+)
+
+// client sends requests and notifications to a server over pipes and collects its messages
+type client struct {
+	t   *testing.T
+	in  io.WriteCloser
+	out *bufio.Reader
+	id  int
+	// notifications holds the notifications received while waiting for responses
+	notifications []clientMessage
+}
+
+type clientMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func newClient(t *testing.T) (*client, chan error) {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(inReader, outWriter).Serve()
+		outWriter.Close()
+	}()
+	return &client{t: t, in: inWriter, out: bufio.NewReader(outReader)}, done
+}
+
+func (c *client) notify(method string, params any) {
+	c.t.Helper()
+	msg := map[string]any{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	if err := transport.WriteMessage(c.in, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// request sends a request and decodes the result of its response into result
+func (c *client) request(method string, params, result any) {
+	c.t.Helper()
+	c.id++
+	if err := transport.WriteMessage(c.in, map[string]any{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		if *msg.ID != c.id {
+			c.t.Fatalf("%s: got response to request %d", method, *msg.ID)
+		}
+		if msg.Error != nil {
+			c.t.Fatalf("%s: %s", method, msg.Error.Message)
+		}
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			c.t.Fatalf("%s: %v", method, err)
+		}
+		return
+	}
+}
+
+// notification returns the next notification, received before or read now
+func (c *client) notification() clientMessage {
+	c.t.Helper()
+	if len(c.notifications) > 0 {
+		msg := c.notifications[0]
+		c.notifications = c.notifications[1:]
+		return msg
+	}
+	return c.read()
+}
+
+func (c *client) read() clientMessage {
+	c.t.Helper()
+	body, err := transport.ReadMessage(c.out)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var msg clientMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+const testDocument = `fn add(int a, int b) int {
+  return a + b
+}
+x := add(1, 2)
+y := "s" - 1
+`
+
+func TestServer(t *testing.T) {
+	c, done := newClient(t)
+	uri := "file:///tmp/test.pd"
+	doc := TextDocumentIdentifier{URI: uri}
+
+	var initialized InitializeResult
+	c.request("initialize", map[string]any{}, &initialized)
+	if !initialized.Capabilities.HoverProvider || initialized.ServerInfo.Name != "palm" {
+		t.Errorf("initialize: got %+v", initialized)
+	}
+	c.notify("initialized", map[string]any{})
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "palm", Version: 1, Text: testDocument}})
+	msg := c.notification()
+	var diagnostics PublishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &diagnostics); err != nil || msg.Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("didOpen: got %s %s, %v", msg.Method, msg.Params, err)
+	}
+	if len(diagnostics.Diagnostics) != 1 || diagnostics.Diagnostics[0].Range.Start != (Position{Line: 4, Character: 9}) {
+		t.Errorf("diagnostics: got %+v, want an error at 4:9", diagnostics.Diagnostics)
+	}
+
+	var hover Hover
+	c.request("textDocument/hover", TextDocumentPositionParams{TextDocument: doc, Position: Position{Line: 3, Character: 0}}, &hover)
+	if !strings.Contains(hover.Contents.Value, "x int") {
+		t.Errorf("hover: got %q, want x int", hover.Contents.Value)
+	}
+
+	var definition Location
+	c.request("textDocument/definition", TextDocumentPositionParams{TextDocument: doc, Position: Position{Line: 3, Character: 6}}, &definition)
+	if definition.URI != uri || definition.Range.Start != (Position{Line: 0, Character: 3}) {
+		t.Errorf("definition: got %+v, want add at 0:3", definition)
+	}
+
+	var symbols []DocumentSymbol
+	c.request("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: doc}, &symbols)
+	var names []string
+	for _, symbol := range symbols {
+		names = append(names, symbol.Name)
+	}
+	if strings.Join(names, " ") != "add x y" || len(symbols[0].Children) != 0 {
+		t.Errorf("documentSymbol: got %+v", symbols)
+	}
+
+	var edits []TextEdit
+	c.request("textDocument/formatting", DocumentFormattingParams{TextDocument: doc}, &edits)
+	if len(edits) != 1 || !strings.HasPrefix(edits[0].NewText, "fn add(int a, int b) int {\n    return a + b\n}") {
+		t.Errorf("formatting: got %+v", edits)
+	}
+
+	var shutdown any
+	c.request("shutdown", nil, &shutdown)
+	c.notify("exit", nil)
+	if err := <-done; err != nil {
+		t.Errorf("exit: %v", err)
+	}
+}
+
+func TestServerExitBeforeShutdown(t *testing.T) {
+	c, done := newClient(t)
+	c.notify("exit", nil)
+	if err := <-done; err == nil {
+		t.Error("exit before shutdown: got no error")
+	}
+}
+
+func TestServerInvalidContentLength(t *testing.T) {
+	for _, header := range []string{"Content-Length: -1\r\n\r\n", "Content-Length: 1099511627776\r\n\r\n"} {
+		c, done := newClient(t)
+		if _, err := io.WriteString(c.in, header); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err == nil {
+			t.Errorf("%q: got no error", header)
+		}
+	}
+}
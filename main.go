@@ -1,90 +1,44 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"myProgrammingLanguage/parse"
 	"os"
-	"strings"
 )
 
-func main() {
-	evalFile()
-}
+const usage = `palm is a tool for running palm programs.
 
-func repl() {
-	reader := bufio.NewReader(os.Stdin)
-	text := ""
-	scope := parse.NewScope(nil)
+Usage:
 
-	for {
-		fmt.Print(">> ")
-		text, _ = reader.ReadString('\n')
+	palm <command> [flags] [file]
 
-		if text == "exit\n" {
-			fmt.Println("Bye!")
-			break
-		}
+The commands are:
 
-		trimmedText := strings.TrimSpace(text)
-		if strings.HasPrefix(trimmedText, "if") {
-			fmt.Println("if statements are not supported yet")
-			continue
-		}
-
-		parser := parse.NewParser("repl", text)
-		tree, _ := parser.Parse()
-		evaluator := NewEvaluator(tree, scope)
-		result, err := evaluator.Evaluate()
-		if err != nil {
-			fmt.Println(err)
-		} else {
-			fmt.Println(result)
-		}
-	}
-}
-
-func evalFile() {
-	// Read from file
-	file, err := os.Open("test.pd")
-	if err != nil {
-		fmt.Println(err)
-	}
+	run       parse and evaluate a file
+	repl      start an interactive session
+	test      run the test functions of the *_test.pd files
+	debug     run a file in the debugger or serve the debug adapter protocol
+	check     report syntax and type errors without running, exits with 1 on errors
+	tokens    print the tokens of a file
+	ast       print the syntax tree of a file
+	fmt       format files in the canonical style
+	lsp       run the language server on standard input and output
+	doc       print the reference of the standard library
+	difftest  run programs with the tree walker and the vm and compare the results
 
-	defer file.Close()
+Use "palm <command> -h" for the flags of a command.
+`
 
-	reader := bufio.NewReader(file)
-	// Read until EOF
-	text := ""
-	for {
-		line, err := reader.ReadString('\n')
-		text += line
-		if err != nil {
-			break
-		}
-	}
-
-	// Parse
-	parser := parse.NewParser("test.pd", text)
-	tree, err := parser.Parse()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	if parser.Errors.HasErrors() {
-		parser.Errors.Print()
-		return
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
 
-	// Evaluate
-	evaluator := NewEvaluator(tree, parse.NewScope(nil))
-
-	result, err := evaluator.Evaluate()
-	if err != nil {
-		fmt.Println(err)
-	} else {
-		fmt.Println(result)
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "palm: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
 	}
 
+	os.Exit(command(os.Args[2:]))
 }
@@ -0,0 +1,143 @@
+// Package module finds and parses the files imported by palm programs.
+package module
+
+import (
+	"errors"
+	"fmt"
+	"myProgrammingLanguage/parse"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Extension is the file extension of palm files, import paths may leave it out
+const Extension = ".pd"
+
+// File is a parsed module, Errors are the syntax errors of the file
+type File struct {
+	Path   string
+	Src    string
+	Tree   *parse.SyntaxTree
+	Errors []parse.Err
+}
+
+// Loader resolves import paths to files and parses them. Every file is read and parsed once.
+type Loader struct {
+	// SearchPath holds the directories searched for imports which are not found relative to the importing file
+	SearchPath []string
+	files      map[string]*File
+	// order holds the paths of the loaded files in the order they were loaded
+	order []string
+}
+
+func NewLoader(searchPath []string) *Loader {
+	return &Loader{SearchPath: searchPath, files: map[string]*File{}}
+}
+
+// DefaultSearchPath returns the directories of the PALM_PATH environment variable, a list separated
+// like PATH
+func DefaultSearchPath() []string {
+	return filepath.SplitList(os.Getenv("PALM_PATH"))
+}
+
+// IsExported reports whether a module member can be used by importing files, exported names start
+// with an upper case letter
+func IsExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// Resolve returns the file of the import path imported by the file from. Paths starting with ./ or ../
+// are only resolved relative to the directory of the importing file, other paths are looked up in the
+// directory of the importing file first and in the search path after it.
+func (l *Loader) Resolve(path, from string) (string, error) {
+	if path == "" {
+		return "", errors.New("empty import path")
+	}
+	if filepath.Ext(path) != Extension {
+		path += Extension
+	}
+	if filepath.IsAbs(path) {
+		return l.find(path, path)
+	}
+
+	dirs := []string{filepath.Dir(from)}
+	if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+		dirs = append(dirs, l.SearchPath...)
+	}
+	for _, dir := range dirs {
+		if file, err := l.find(path, filepath.Join(dir, path)); err == nil {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("cannot find module %s in %s", path, strings.Join(dirs, string(filepath.ListSeparator)))
+}
+
+// find returns the cleaned path of the file if it is a regular file
+func (l *Loader) find(path, file string) (string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("module %s is a directory", path)
+	}
+	return filepath.Clean(file), nil
+}
+
+// Load resolves the import path like Resolve and returns the parsed file
+func (l *Loader) Load(path, from string) (*File, error) {
+	resolved, err := l.Resolve(path, from)
+	if err != nil {
+		return nil, err
+	}
+	if file, ok := l.files[resolved]; ok {
+		return file, nil
+	}
+
+	src, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, err
+	}
+	parser := parse.NewParser(resolved, string(src))
+	tree, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{Path: resolved, Src: string(src), Tree: tree, Errors: parser.Errors.GetErrors()}
+	l.files[resolved] = file
+	l.order = append(l.order, resolved)
+	return file, nil
+}
+
+// Files returns the loaded files in the order they were loaded
+func (l *Loader) Files() []*File {
+	files := make([]*File, len(l.order))
+	for i, path := range l.order {
+		files[i] = l.files[path]
+	}
+	return files
+}
+
+// Chain holds the files of the modules being loaded, each one imported by the one before it
+type Chain []string
+
+// Enter adds the file to the chain, it returns an error if the file is already being loaded
+func (c *Chain) Enter(file string) error {
+	for i, loading := range *c {
+		if loading == file {
+			cycle := append(append([]string{}, (*c)[i:]...), file)
+			return fmt.Errorf("import cycle: %s", strings.Join(cycle, " imports "))
+		}
+	}
+	*c = append(*c, file)
+	return nil
+}
+
+// Leave removes the last file of the chain
+func (c *Chain) Leave() {
+	*c = (*c)[:len(*c)-1]
+}
@@ -0,0 +1,140 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files in a temporary directory and returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.pd":          "",
+		"util.pd":          "",
+		"lib/util.pd":      "",
+		"lib/strs.pd":      "",
+		"lib/dir.pd/x.pd":  "",
+		"path/colors.pd":   "",
+		"path/util.pd":     "",
+		"path/nested/a.pd": "",
+	})
+	loader := NewLoader([]string{filepath.Join(dir, "path")})
+	main := filepath.Join(dir, "main.pd")
+	lib := filepath.Join(dir, "lib", "strs.pd")
+	tests := []struct {
+		path, from, want, err string
+	}{
+		{"./util", main, "util.pd", ""},
+		{"./util.pd", main, "util.pd", ""},
+		{"util", main, "util.pd", ""},
+		{"./util", lib, "lib/util.pd", ""},
+		{"../util", lib, "util.pd", ""},
+		{"colors", main, "path/colors.pd", ""},
+		{"nested/a", lib, "path/nested/a.pd", ""},
+		{"./colors", main, "", "cannot find module ./colors.pd in " + dir},
+		{"missing", main, "", "cannot find module missing.pd in " + dir + string(filepath.ListSeparator) + filepath.Join(dir, "path")},
+		{"./lib/dir", main, "", "cannot find module ./lib/dir.pd"},
+		{filepath.Join(dir, "lib", "dir"), main, "", "module " + filepath.Join(dir, "lib", "dir.pd") + " is a directory"},
+		{filepath.Join(dir, "util"), lib, "util.pd", ""},
+		{"", main, "", "empty import path"},
+	}
+	for _, test := range tests {
+		got, err := loader.Resolve(test.path, test.from)
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("Resolve(%q, %q): got %q, %v, want error %q", test.path, test.from, got, err, test.err)
+			}
+			continue
+		}
+		if want := filepath.Join(dir, test.want); err != nil || got != want {
+			t.Errorf("Resolve(%q, %q): got %q, %v, want %q", test.path, test.from, got, err, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.pd":   "",
+		"a.pd":      "fn A() int { return 1 }",
+		"b.pd":      "x := )",
+		"lib/c.pd":  "C := 3",
+		"unused.pd": "",
+	})
+	loader := NewLoader(nil)
+	main := filepath.Join(dir, "main.pd")
+	var loaded []*File
+	for _, path := range []string{"./a", "./lib/c", "./a", "./b", "./lib/../a"} {
+		file, err := loader.Load(path, main)
+		if err != nil {
+			t.Fatalf("Load(%q): %v", path, err)
+		}
+		loaded = append(loaded, file)
+	}
+	if loaded[0] != loaded[2] || loaded[0] != loaded[4] {
+		t.Errorf("loading a.pd again parsed it again")
+	}
+	if loaded[0].Src != "fn A() int { return 1 }" || loaded[0].Tree == nil || len(loaded[0].Errors) != 0 {
+		t.Errorf("a.pd: got %+v", loaded[0])
+	}
+	if len(loaded[3].Errors) == 0 {
+		t.Errorf("b.pd: got no syntax errors")
+	}
+	var paths []string
+	for _, file := range loader.Files() {
+		paths = append(paths, file.Path)
+	}
+	want := []string{filepath.Join(dir, "a.pd"), filepath.Join(dir, "lib", "c.pd"), filepath.Join(dir, "b.pd")}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("Files: got %v, want %v", paths, want)
+	}
+	if _, err := loader.Load("./missing", main); err == nil {
+		t.Errorf("Load of a missing module: got no error")
+	}
+}
+
+func TestIsExported(t *testing.T) {
+	for name, want := range map[string]bool{"Add": true, "add": false, "Ärger": true, "ärger": false, "": false} {
+		if got := IsExported(name); got != want {
+			t.Errorf("IsExported(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	var chain Chain
+	for _, file := range []string{"main.pd", "a.pd", "b.pd"} {
+		if err := chain.Enter(file); err != nil {
+			t.Fatalf("Enter(%s): %v", file, err)
+		}
+	}
+	if err := chain.Enter("a.pd"); err == nil || err.Error() != "import cycle: a.pd imports b.pd imports a.pd" {
+		t.Errorf("got %v, want the cycle of a.pd and b.pd", err)
+	}
+	if err := chain.Enter("b.pd"); err == nil || err.Error() != "import cycle: b.pd imports b.pd" {
+		t.Errorf("got %v, want b.pd importing itself", err)
+	}
+	chain.Leave()
+	// b.pd isn't being loaded anymore, so importing it from a.pd again is fine
+	if err := chain.Enter("b.pd"); err != nil {
+		t.Errorf("got %v entering b.pd after leaving it", err)
+	}
+	if len(chain) != 3 {
+		t.Errorf("got chain %v, want 3 files", chain)
+	}
+}
@@ -0,0 +1,156 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiCyan   = "\x1b[1;36m"
+	ansiBlue   = "\x1b[1;34m"
+)
+
+// Renderer writes diagnostics together with the source lines they refer to, like
+//
+//	main.pd:1:8: error: mismatched types int and string for operator +
+//	  1 | x := 1 + "a"
+//	    |        ^
+type Renderer struct {
+	// Color enables ANSI colors
+	Color   bool
+	sources map[string][]string
+}
+
+func NewRenderer(color bool) *Renderer {
+	return &Renderer{Color: color, sources: map[string][]string{}}
+}
+
+// AddSource registers the source of a file, diagnostics of unknown files are rendered without source lines
+func (r *Renderer) AddSource(name, src string) {
+	r.sources[name] = strings.Split(src, "\n")
+}
+
+// RenderAll renders the diagnostics in order
+func (r *Renderer) RenderAll(w io.Writer, errs []Err) {
+	for _, err := range errs {
+		r.Render(w, err)
+	}
+}
+
+// Render writes the message of the diagnostic, the source lines of its span and its labels and
+// then its notes
+func (r *Renderer) Render(w io.Writer, err Err) {
+	fmt.Fprintf(w, "%s %s %s\n",
+		r.paint(ansiBold, fmt.Sprintf("%s:%d:%d:", err.File, err.Loc.Start.Line+1, err.Loc.Start.Col+1)),
+		r.paint(kindColor(err.Kind), err.Kind.String()+":"),
+		r.paint(ansiBold, err.Msg))
+
+	spans := []span{{loc: err.Loc, len: err.Len, marker: '^', color: kindColor(err.Kind)}}
+	for _, label := range err.Labels {
+		spans = append(spans, span{loc: label.Loc, len: label.Len, msg: label.Msg, marker: '-', color: ansiBlue})
+	}
+	r.renderSpans(w, err.File, spans)
+
+	for _, note := range err.Notes {
+		r.Render(w, note)
+	}
+}
+
+// span is an underlined part of a source line
+type span struct {
+	loc    TokenLocation
+	len    int
+	msg    string
+	marker rune
+	color  string
+}
+
+func (r *Renderer) renderSpans(w io.Writer, file string, spans []span) {
+	lines, ok := r.sources[file]
+	if !ok {
+		return
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].loc.Start.Offset < spans[j].loc.Start.Offset
+	})
+	last := spans[len(spans)-1].loc.Start.Line + 1
+	width := len(fmt.Sprint(last))
+	gutter := r.paint(ansiBlue, strings.Repeat(" ", width)+" |")
+
+	prevLine := -1
+	for _, s := range spans {
+		line := s.loc.Start.Line
+		if line < 0 || line >= len(lines) {
+			continue
+		}
+		text := strings.TrimRight(lines[line], "\r")
+		if line != prevLine {
+			if prevLine >= 0 && line > prevLine+1 {
+				fmt.Fprintln(w, r.paint(ansiBlue, strings.Repeat(".", width+2)))
+			}
+			fmt.Fprintf(w, "%s %s\n", r.paint(ansiBlue, fmt.Sprintf("%*d |", width, line+1)), text)
+			prevLine = line
+		}
+
+		col := s.loc.Start.Col
+		if col > len(text) {
+			col = len(text)
+		}
+		underline := strings.Repeat(string(s.marker), spanWidth(text[col:], s.len))
+		if s.msg != "" {
+			underline += " " + s.msg
+		}
+		fmt.Fprintf(w, "%s %s%s\n", gutter, indentation(text[:col]), r.paint(s.color, underline))
+	}
+}
+
+// spanWidth returns the number of characters underlined for a span of n bytes starting at text,
+// spans reaching past the end of the line are cut at the end of the line, empty spans are one character wide
+func spanWidth(text string, n int) int {
+	if n > len(text) {
+		n = len(text)
+	}
+	if width := utf8.RuneCountInString(text[:n]); width > 0 {
+		return width
+	}
+	return 1
+}
+
+// indentation returns the whitespace which puts a marker below the character following prefix,
+// tabs are kept so that the marker lines up with the source line
+func indentation(prefix string) string {
+	var builder strings.Builder
+	for _, c := range prefix {
+		if c == '\t' {
+			builder.WriteRune('\t')
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+	return builder.String()
+}
+
+func (r *Renderer) paint(color, s string) string {
+	if !r.Color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+func kindColor(kind ErrorKind) string {
+	switch kind {
+	case Warning:
+		return ansiYellow
+	case Note:
+		return ansiCyan
+	}
+	return ansiRed
+}
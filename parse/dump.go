@@ -0,0 +1,185 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+	tokenType = reflect.TypeOf(Token{})
+)
+
+// Fprint writes the tree of given node to w in an indented human readable form.
+// Tokens and raw values of a node are printed on its line, child nodes on the following lines.
+func Fprint(w io.Writer, node Node) error {
+	builder := strings.Builder{}
+	if node != nil {
+		dumpValue(&builder, "", reflect.ValueOf(node), 0)
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func dumpValue(builder *strings.Builder, label string, v reflect.Value, depth int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	builder.WriteString(strings.Repeat("  ", depth))
+	if label != "" {
+		builder.WriteString(label)
+		builder.WriteString(": ")
+	}
+	if node, ok := v.Addr().Interface().(Node); ok {
+		builder.WriteString(node.Kind().String())
+	} else {
+		builder.WriteString(v.Type().Name())
+	}
+
+	// attributes first, so that the children are printed below their parent line
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		value := v.Field(i)
+		switch {
+		case field.Type == tokenType:
+			token := value.Interface().(Token)
+			if token.Val != "" {
+				builder.WriteString(fmt.Sprintf(" %s=%s", field.Name, strconv.Quote(token.Val)))
+			}
+		case field.Type.Kind() == reflect.String:
+			builder.WriteString(fmt.Sprintf(" %s=%s", field.Name, strconv.Quote(value.String())))
+		}
+	}
+
+	if node, ok := v.Addr().Interface().(Node); ok {
+		if loc, ok := nodeLocation(node); ok {
+			builder.WriteString(fmt.Sprintf(" (%d:%d)", loc.Line+1, loc.Col+1))
+		}
+	}
+	builder.WriteString("\n")
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		value := v.Field(i)
+		switch {
+		case field.Type == nodeType || (field.Type.Kind() == reflect.Pointer && field.Type.Implements(nodeType)):
+			dumpValue(builder, field.Name, value, depth+1)
+		case field.Type.Kind() == reflect.Slice:
+			for j := 0; j < value.Len(); j++ {
+				dumpValue(builder, fmt.Sprintf("%s[%d]", field.Name, j), value.Index(j), depth+1)
+			}
+		}
+	}
+}
+
+// Inspect calls f for the node and the nodes below it in depth-first order, the nodes below a node are
+// skipped if f returns false for it
+func Inspect(node Node, f func(Node) bool) {
+	if node != nil {
+		inspectValue(reflect.ValueOf(node), f)
+	}
+}
+
+func inspectValue(v reflect.Value, f func(Node) bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			inspectValue(v.Index(i), f)
+		}
+		return
+	case v.Kind() != reflect.Struct || v.Type() == tokenType:
+		return
+	}
+	if node, ok := v.Addr().Interface().(Node); ok && !f(node) {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.IsExported() && !field.Anonymous {
+			inspectValue(v.Field(i), f)
+		}
+	}
+}
+
+// ToJSON converts the tree of given node to maps and slices which can be marshaled to JSON.
+// Every node has a "Kind" key, tokens are kept as they are and child nodes are converted recursively.
+func ToJSON(node Node) any {
+	if node == nil {
+		return nil
+	}
+	return jsonValue(reflect.ValueOf(node))
+}
+
+func jsonValue(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = jsonValue(v.Index(i))
+		}
+		return values
+	case reflect.Struct:
+		if v.Type() == tokenType {
+			return v.Interface()
+		}
+	default:
+		return v.Interface()
+	}
+
+	values := map[string]any{}
+	if node, ok := v.Addr().Interface().(Node); ok {
+		values["Kind"] = node.Kind().String()
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous || field.Name == "Pos" {
+			continue
+		}
+		values[field.Name] = jsonValue(v.Field(i))
+	}
+	return values
+}
+
+// nodeLocation returns the start location of the first token held directly by the node
+func nodeLocation(node Node) (Location, bool) {
+	v := reflect.ValueOf(node).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Type != tokenType {
+			continue
+		}
+		token := v.Field(i).Interface().(Token)
+		if token.Val != "" {
+			return token.Loc.Start, true
+		}
+	}
+	return Location{}, false
+}
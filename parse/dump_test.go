@@ -0,0 +1,24 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	src := "fn f(int[] xs) int {\n    return xs[0] + 1\n}\nif f([2]) > 2 { x := -1 }"
+	tree, err := NewParser("inspect.pd", src).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	Inspect(tree.Root, func(node Node) bool {
+		kinds = append(kinds, node.Kind().String())
+		// the body of the if statement is skipped
+		return node.Kind() != NodeBlockStatement || FirstToken(node).Loc.Start.Line == 0
+	})
+	want := "Program Function Type Type Type BlockStatement ReturnStatement BinaryExpression IndexExpression CallExpression Number Number IfStatement BinaryExpression CallExpression CallExpression ArrayLiteral Number Number BlockStatement"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 )
@@ -25,12 +26,64 @@ func (k ErrorKind) String() string {
 	return ""
 }
 
+func (k ErrorKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Err is a diagnostic at the span of Len bytes starting at Loc.
+// Labels mark other spans in the same file, Notes are further diagnostics like the location of a previous definition.
 type Err struct {
-	File string
-	Len  int
-	Loc  TokenLocation
-	Msg  string
-	Kind ErrorKind
+	File   string
+	Len    int
+	Loc    TokenLocation
+	Msg    string
+	Kind   ErrorKind
+	Labels []Label
+	Notes  []Err
+}
+
+// Label marks a span of Len bytes starting at Loc with a message
+type Label struct {
+	Len int
+	Loc TokenLocation
+	Msg string
+}
+
+// jsonErr is the JSON form of a diagnostic, lines and columns are 1-based like in the text form
+// and length is the length of the span in bytes
+type jsonErr struct {
+	File     string      `json:"file"`
+	Line     int         `json:"line"`
+	Column   int         `json:"column"`
+	Length   int         `json:"length"`
+	Severity ErrorKind   `json:"severity"`
+	Message  string      `json:"message"`
+	Labels   []jsonLabel `json:"labels,omitempty"`
+	Notes    []Err       `json:"notes,omitempty"`
+}
+
+type jsonLabel struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Length  int    `json:"length"`
+	Message string `json:"message"`
+}
+
+func (e Err) MarshalJSON() ([]byte, error) {
+	labels := make([]jsonLabel, len(e.Labels))
+	for i, label := range e.Labels {
+		labels[i] = jsonLabel{Line: label.Loc.Start.Line + 1, Column: label.Loc.Start.Col + 1, Length: label.Len, Message: label.Msg}
+	}
+	return json.Marshal(jsonErr{
+		File:     e.File,
+		Line:     e.Loc.Start.Line + 1,
+		Column:   e.Loc.Start.Col + 1,
+		Length:   e.Len,
+		Severity: e.Kind,
+		Message:  e.Msg,
+		Labels:   labels,
+		Notes:    e.Notes,
+	})
 }
 
 func (e Err) String() string {
@@ -46,6 +99,10 @@ type ErrorContainer struct {
 	mu     *sync.Mutex
 }
 
+func NewErrorContainer() *ErrorContainer {
+	return &ErrorContainer{Errors: []Err{}, mu: &sync.Mutex{}}
+}
+
 func (e *ErrorContainer) AddError(err Err) {
 	e.mu.Lock()
 	e.Errors = append(e.Errors, err)
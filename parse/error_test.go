@@ -0,0 +1,63 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrJSON(t *testing.T) {
+	err := Err{
+		File:   "main.pd",
+		Len:    1,
+		Loc:    TokenLocation{Start: Location{Offset: 11, Line: 1, Col: 9}},
+		Msg:    "mismatched types",
+		Kind:   Error,
+		Labels: []Label{{Len: 3, Loc: TokenLocation{Start: Location{Offset: 2, Line: 0, Col: 2}}, Msg: "declared here"}},
+		Notes:  []Err{{File: "main.pd", Len: 1, Loc: TokenLocation{}, Msg: "a note", Kind: Note}},
+	}
+	got, e := json.Marshal([]Err{err})
+	if e != nil {
+		t.Fatal(e)
+	}
+	want := `[{"file":"main.pd","line":2,"column":10,"length":1,"severity":"error","message":"mismatched types",` +
+		`"labels":[{"line":1,"column":3,"length":3,"message":"declared here"}],` +
+		`"notes":[{"file":"main.pd","line":1,"column":1,"length":1,"severity":"note","message":"a note"}]}]`
+	if string(got) != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+// TestSyntaxErrors checks that every error is reported once, without errors caused by earlier ones
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"j := fn g() int { return 1 }\nj()", "1:9: function literal can't have a name"},
+		{"fn f() { println(fn g() {}) }", "1:21: function literal can't have a name"},
+		{"for x := 5 { break }", "1:5: expected for loop condition got statement"},
+		{"for int i = 0 {\n}\nx := 1", "1:5: expected for loop condition got statement"},
+		{"for x < 5 { break }", ""},
+		// a missing brace doesn't start a block taking the rest of the file
+		{"fn add(int a, int b) int return a + b\nx := 1", "1:26: expected LBRACE got RETURN"},
+		{"for i := 0 i < 3; i += 1 { }\nx := 1", "1:12: expected SEMICOLON got IDENT"},
+		{"fn f() ) { }\nx := 1", "1:8: expected one of them <INT, FLOAT, BOOL, STRING_TYPE, INTERFACE, FN, IDENT, > got <RPAREN>"},
+		{"fn f1() { }\nx := 1", "1:5: expected LPAREN got NUMBER"},
+		{"fn f() int return 1\nx := )\ny := 2", "1:12: expected LBRACE got RETURN\n2:6: expected expression got RPAREN"},
+	}
+	for _, test := range tests {
+		parser := NewParser("test.pd", test.src)
+		if _, err := parser.Parse(); err != nil {
+			t.Fatalf("%q: %v", test.src, err)
+		}
+		var got []string
+		for _, err := range parser.Errors.GetErrors() {
+			got = append(got, fmt.Sprintf("%d:%d: %s", err.Loc.Start.Line+1, err.Loc.Start.Col+1, err.Msg))
+		}
+		if strings.Join(got, "\n") != test.want {
+			t.Errorf("%q: got errors\n%s\nwant\n%s", test.src, strings.Join(got, "\n"), test.want)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package parse
+
+import (
+	"math"
+	"strings"
+)
+
+const indentUnit = "    "
+
+// Format returns the canonical source of the tree. Statements are written on their own lines, blocks
+// are indented by four spaces with the opening brace on the line of the statement, binary operators
+// are surrounded by spaces and one blank line is kept where the source has blank lines between statements.
+// Comments are kept in place. Lists with line comments are written with one item per line, other line
+// comments inside of statements end the line and the statement continues indented on the next one.
+// Formatting the output again returns it unchanged.
+func Format(tree *SyntaxTree) string {
+	if tree == nil || tree.Root == nil {
+		return ""
+	}
+	f := &formatter{comments: tree.Comments}
+	f.statements(tree.Root.(*ProgramNode).Nodes, -1, math.MaxInt)
+	if f.builder.Len() > 0 {
+		f.builder.WriteString("\n")
+	}
+	return f.builder.String()
+}
+
+type formatter struct {
+	builder strings.Builder
+	indent  int
+	// lineStart is set after a newline, the indentation is written with the next text
+	lineStart bool
+	// continued is set after a line comment inside of a statement, the next line is indented once more
+	continued bool
+	// comments are the comments of the source, the ones before next are written
+	comments []Comment
+	next     int
+}
+
+func (f *formatter) write(s string) {
+	if f.lineStart {
+		indent := f.indent
+		if f.continued {
+			indent++
+		}
+		f.builder.WriteString(strings.Repeat(indentUnit, indent))
+		f.lineStart, f.continued = false, false
+	}
+	f.builder.WriteString(s)
+}
+
+func (f *formatter) newline() {
+	f.builder.WriteString("\n")
+	f.lineStart = true
+}
+
+// token writes the token, the comments before it are written first. Line comments end the line,
+// the source has a line break there too.
+func (f *formatter) token(token Token) {
+	for f.commentsBefore(token.Loc.Start.Offset) {
+		text := f.comments[f.next].Text
+		f.next++
+		if !strings.HasPrefix(text, "//") && !closing(token) {
+			f.write(text + " ")
+			continue
+		}
+		// line comments and comments before closing brackets are separated from the code before them instead
+		if out := f.builder.String(); !f.lineStart && out != "" && !strings.ContainsRune(" ([{", rune(out[len(out)-1])) {
+			f.write(" ")
+		}
+		f.write(text)
+		if strings.HasPrefix(text, "//") {
+			f.newline()
+			f.continued = true
+		}
+	}
+	f.write(token.Val)
+}
+
+// closing reports whether the token closes brackets
+func closing(token Token) bool {
+	return token.Kind == RPAREN || token.Kind == RBRACKET || token.Kind == RBRACE
+}
+
+// commentsBefore reports whether there are comments left before the offset
+func (f *formatter) commentsBefore(offset int) bool {
+	return f.next < len(f.comments) && f.comments[f.next].Loc.Start.Offset < offset
+}
+
+// lineCommentsBefore reports whether there are line comments left before the offset
+func (f *formatter) lineCommentsBefore(offset int) bool {
+	for _, comment := range f.comments[f.next:] {
+		if comment.Loc.Start.Offset >= offset {
+			return false
+		}
+		if strings.HasPrefix(comment.Text, "//") {
+			return true
+		}
+	}
+	return false
+}
+
+// statements writes the statements and the comments before the end offset on their own lines,
+// starting on the current line. prevLine is -1, it is the source line of the last written item later on.
+func (f *formatter) statements(nodes []Node, prevLine int, end int) {
+	for i, node := range nodes {
+		first := FirstToken(node)
+		prevLine = f.lineComments(first.Loc.Start.Offset, prevLine)
+		f.separate(first.Loc.Start.Line, prevLine)
+		f.node(node)
+
+		limit := end
+		if i+1 < len(nodes) {
+			limit = FirstToken(nodes[i+1]).Loc.Start.Offset
+		}
+		prevLine = f.trailingComments(LastToken(node).Loc.End.Line, limit)
+	}
+	f.lineComments(end, prevLine)
+}
+
+// separate starts the line of a statement or comment starting at the given source line, there is a blank
+// line between them if there is one in the source. Nothing is written for the first item of a list.
+func (f *formatter) separate(line, prevLine int) {
+	if prevLine < 0 {
+		return
+	}
+	f.newline()
+	if line > prevLine+1 {
+		f.newline()
+	}
+}
+
+// lineComments writes the comments before the end offset on their own lines, it returns the source line of the last one
+func (f *formatter) lineComments(end int, prevLine int) int {
+	for f.commentsBefore(end) {
+		comment := f.comments[f.next]
+		f.next++
+		f.separate(comment.Loc.Start.Line, prevLine)
+		f.write(comment.Text)
+		prevLine = comment.Loc.End.Line
+	}
+	return prevLine
+}
+
+// trailingComments writes the comments starting on the source line before the limit offset at the end
+// of the current line, it returns the source line the last of them ends on
+func (f *formatter) trailingComments(line int, limit int) int {
+	for f.commentsBefore(limit) && f.comments[f.next].Loc.Start.Line == line {
+		comment := f.comments[f.next]
+		f.next++
+		f.write(" " + comment.Text)
+		line = comment.Loc.End.Line
+	}
+	return line
+}
+
+func (f *formatter) block(block *BlockStatementNode) {
+	f.token(block.Left)
+	end := block.Right.Loc.Start.Offset
+	if len(block.Nodes) == 0 && !f.commentsBefore(end) {
+		f.token(block.Right)
+		return
+	}
+
+	f.indent++
+	f.trailingComments(block.Left.Loc.Start.Line, end)
+	f.newline()
+	f.statements(block.Nodes, -1, end)
+	f.indent--
+	f.newline()
+	f.token(block.Right)
+}
+
+func (f *formatter) node(node Node) {
+	switch n := node.(type) {
+	case *NumberNode:
+		f.token(n.Token)
+	case *BooleanNode:
+		f.token(n.Token)
+	case *StringNode:
+		f.token(n.Token)
+	case *BinaryExpressionNode:
+		f.node(n.Left)
+		f.write(" ")
+		f.token(n.Op)
+		f.write(" ")
+		f.node(n.Right)
+	case *ParenthesisedExpressionNode:
+		f.token(n.Left)
+		f.node(n.Expression)
+		f.token(n.Right)
+	case *UnaryExpressionNode:
+		f.token(n.Op)
+		// a space keeps operators like - - apart, the lexer would read -- as one operator
+		if _, ok := n.Right.(*UnaryExpressionNode); ok {
+			f.write(" ")
+		}
+		f.node(n.Right)
+	case *AssignmentExpressionNode:
+		if n.Target != nil {
+			f.node(n.Target)
+		} else {
+			f.token(n.Identifier)
+		}
+		f.write(" ")
+		f.token(n.Op)
+		f.write(" ")
+		f.node(n.Right)
+	case *CallExpressionNode:
+		if !n.IsCall() {
+			f.token(n.Identifier)
+			return
+		}
+		f.node(n.Callee)
+		f.list(n.LParen, f.nodeItems(n.Args), n.RParen)
+	case *BlockStatementNode:
+		f.block(n)
+	case *IfStatementNode:
+		f.token(n.IfToken)
+		f.write(" ")
+		f.node(n.Expression)
+		f.write(" ")
+		f.node(n.Body)
+		if n.Else != nil {
+			f.write(" ")
+			// the else token of else if isn't kept in the tree
+			if _, ok := n.Else.(*IfStatementNode); ok {
+				f.write("else ")
+			}
+			f.node(n.Else)
+		}
+	case *ElseStatementNode:
+		f.token(n.Else)
+		f.write(" ")
+		f.node(n.Body)
+	case *VariableDeclarationStatementNode:
+		if n.HasTypeToken {
+			f.typ(n.Type)
+			f.write(" ")
+		}
+		f.token(n.Identifier)
+		f.write(" ")
+		f.token(n.DeclareToken)
+		f.write(" ")
+		f.node(n.Expression)
+	case *TypeNode:
+		f.typ(n)
+	case *ArrayLiteralNode:
+		f.list(n.LBracket, f.nodeItems(n.Elements), n.RBracket)
+	case *IndexExpressionNode:
+		f.node(n.Left)
+		f.token(n.LBracket)
+		f.node(n.Index)
+		f.token(n.RBracket)
+	case *FunctionNode:
+		f.token(n.FnToken)
+		if n.Name.Val != "" {
+			f.write(" ")
+			f.token(n.Name)
+		}
+		params := make([]listItem, len(n.Params))
+		for i, param := range n.Params {
+			param := param
+			params[i] = listItem{first: FirstToken(param.Type), last: param.Name, write: func() {
+				f.typ(param.Type)
+				f.write(" ")
+				f.token(param.Name)
+			}}
+		}
+		f.list(n.LParen, params, n.RParen)
+		if n.ReturnType != nil {
+			f.write(" ")
+			f.typ(n.ReturnType)
+		}
+		f.write(" ")
+		f.block(n.Body)
+	case *ReturnStatementNode:
+		f.token(n.ReturnToken)
+		if n.Expression != nil {
+			f.write(" ")
+			f.node(n.Expression)
+		}
+	case *ForStatementNode:
+		f.token(n.ForToken)
+		f.write(" ")
+		if n.Init != nil || n.Post != nil {
+			f.optional(n.Init)
+			f.write("; ")
+			f.optional(n.Condition)
+			f.write("; ")
+			f.optional(n.Post)
+			f.write(" ")
+		} else if n.Condition != nil {
+			f.node(n.Condition)
+			f.write(" ")
+		}
+		f.node(n.Body)
+	case *BranchStatementNode:
+		f.token(n.Token)
+	case *TypeDeclarationNode:
+		f.token(n.TypeToken)
+		f.write(" ")
+		f.token(n.Name)
+		f.write(" ")
+		f.token(n.AssignToken)
+		f.write(" ")
+		f.typ(n.Type)
+	case *StructLiteralNode:
+		f.token(n.TypeName)
+		fields := make([]listItem, len(n.Fields))
+		for i, field := range n.Fields {
+			field := field
+			fields[i] = listItem{first: field.Name, last: LastToken(field.Value), write: func() {
+				f.token(field.Name)
+				f.write(": ")
+				f.node(field.Value)
+			}}
+		}
+		f.list(n.LBrace, fields, n.RBrace)
+	case *SelectorExpressionNode:
+		f.node(n.Left)
+		f.token(n.Dot)
+		f.token(n.Field)
+	case *ImportNode:
+		f.token(n.ImportToken)
+		f.write(" ")
+		f.token(n.Path.Token)
+	case *ProgramNode:
+		f.statements(n.Nodes, -1, math.MaxInt)
+	default:
+		f.write(node.String())
+	}
+}
+
+// optional writes the node of a for clause, omitted clauses are nil and leave the clause empty
+func (f *formatter) optional(node Node) {
+	if node != nil {
+		f.node(node)
+	}
+}
+
+// listItem is an item of a list like the arguments of a call, first and last are the tokens it starts and ends with
+type listItem struct {
+	first, last Token
+	write       func()
+}
+
+func (f *formatter) nodeItems(nodes []Node) []listItem {
+	items := make([]listItem, len(nodes))
+	for i, node := range nodes {
+		node := node
+		items[i] = listItem{first: FirstToken(node), last: LastToken(node), write: func() { f.node(node) }}
+	}
+	return items
+}
+
+// list writes the items between the brackets separated by commas. Lists with line comments are written
+// like statements with one item per line, so that the comments keep their line breaks.
+func (f *formatter) list(open Token, items []listItem, close Token) {
+	f.token(open)
+	end := close.Loc.Start.Offset
+	if !f.lineCommentsBefore(end) {
+		for i, item := range items {
+			if i > 0 {
+				f.write(", ")
+			}
+			item.write()
+		}
+		f.token(close)
+		return
+	}
+
+	f.indent++
+	limit := end
+	if len(items) > 0 {
+		limit = items[0].first.Loc.Start.Offset
+	}
+	f.trailingComments(open.Loc.Start.Line, limit)
+	f.newline()
+	prevLine := -1
+	for i, item := range items {
+		prevLine = f.lineComments(item.first.Loc.Start.Offset, prevLine)
+		f.separate(item.first.Loc.Start.Line, prevLine)
+		item.write()
+		f.write(",")
+
+		limit := end
+		if i+1 < len(items) {
+			limit = items[i+1].first.Loc.Start.Offset
+		}
+		prevLine = f.trailingComments(item.last.Loc.End.Line, limit)
+	}
+	f.lineComments(end, prevLine)
+	f.indent--
+	f.newline()
+	f.token(close)
+}
+
+// typ writes a type annotation, struct types have one field per line
+func (f *formatter) typ(typ *TypeNode) {
+	switch {
+	case typ.IsArray():
+		f.typ(typ.Elem)
+		f.token(typ.LBracket)
+		f.token(typ.RBracket)
+	case typ.IsStruct():
+		f.token(typ.Struct)
+		f.write(" ")
+		f.token(typ.LBrace)
+		if len(typ.Fields) == 0 {
+			f.token(typ.RBrace)
+			return
+		}
+		// fields are written like statements, with their comments on their own lines or at the line end
+		end := typ.RBrace.Loc.Start.Offset
+		f.indent++
+		f.newline()
+		prevLine := -1
+		for i, field := range typ.Fields {
+			first := FirstToken(field.Type)
+			prevLine = f.lineComments(first.Loc.Start.Offset, prevLine)
+			f.separate(first.Loc.Start.Line, prevLine)
+			f.typ(field.Type)
+			f.write(" ")
+			f.token(field.Name)
+
+			limit := end
+			if i+1 < len(typ.Fields) {
+				limit = FirstToken(typ.Fields[i+1].Type).Loc.Start.Offset
+			}
+			prevLine = f.trailingComments(field.Name.Loc.End.Line, limit)
+		}
+		f.lineComments(end, prevLine)
+		f.indent--
+		f.newline()
+		f.token(typ.RBrace)
+	default:
+		f.token(typ.Name)
+	}
+}
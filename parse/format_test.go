@@ -0,0 +1,35 @@
+package parse
+
+import "testing"
+
+func TestFormatComments(t *testing.T) {
+	tests := map[string]string{
+		"a := [\n  1, // one\n  2, // two\n]\n": "a := [\n    1, // one\n    2, // two\n]\n",
+		"fn f(/* none */) {}\n":                 "fn f(/* none */) {}\n",
+		"b := [1, /* inline */ 2]\n":            "b := [1, /* inline */ 2]\n",
+		"d := [1, 2 /* two */]\n":               "d := [1, 2 /* two */]\n",
+		"y := 1 + // c\n2\n":                    "y := 1 + // c\n    2\n",
+		"f(1, // one\n\n// two\n2)\n":           "f(\n    1, // one\n\n    // two\n    2,\n)\n",
+		"fn g(int a, // a\nint b) {}\n":         "fn g(\n    int a, // a\n    int b,\n) {}\n",
+		"p := P{x: 1, // x\n}\n":                "p := P{\n    x: 1, // x\n}\n",
+	}
+	for src, want := range tests {
+		parser := NewParser("test.pd", src)
+		tree, err := parser.Parse()
+		if err != nil || parser.Errors.HasErrors() {
+			t.Fatalf("%q: %v %v", src, err, parser.Errors.GetErrors())
+		}
+		got := Format(tree)
+		if got != want {
+			t.Errorf("%q: got\n%s\nwant\n%s", src, got, want)
+			continue
+		}
+
+		// the output is formatted
+		parser = NewParser("test.pd", got)
+		tree, _ = parser.Parse()
+		if again := Format(tree); again != got {
+			t.Errorf("%q: formatting again got\n%s", src, again)
+		}
+	}
+}
@@ -60,6 +60,23 @@ const (
 	LBRACE       // {
 	RBRACE       // }
 	BOOL         // bool
+	FLOAT        // float
+	STRING       // "abc"
+	STRING_TYPE  // string
+	LBRACKET     // [
+	RBRACKET     // ]
+	COMMA        // ,
+	INTERFACE    // interface
+	FN           // fn
+	RETURN       // return
+	FOR          // for
+	BREAK        // break
+	CONTINUE     // continue
+	SEMICOLON    // ;
+	TYPE         // type
+	STRUCT       // struct
+	DOT          // .
+	IMPORT       // import
 )
 
 var emptyToken = Token{
@@ -102,6 +119,10 @@ func (k TokenKind) String() string {
 		return "EOF"
 	case BADTOKEN:
 		return "BADTOKEN"
+	case UNEXPECTED:
+		return "UNEXPECTED"
+	case EMPTY:
+		return "EMPTY"
 	case PLUS:
 		return "PLUS"
 	case MINUS:
@@ -138,6 +159,14 @@ func (k TokenKind) String() string {
 		return "DECLARE"
 	case COLON:
 		return "COLON"
+	case BITAND:
+		return "BITAND"
+	case BITOR:
+		return "BITOR"
+	case AND:
+		return "AND"
+	case OR:
+		return "OR"
 	case XOR:
 		return "XOR"
 	case PLUS_ASSIGN:
@@ -172,11 +201,49 @@ func (k TokenKind) String() string {
 		return "RBRACE"
 	case BOOL:
 		return "BOOL"
+	case FLOAT:
+		return "FLOAT"
+	case STRING:
+		return "STRING"
+	case STRING_TYPE:
+		return "STRING_TYPE"
+	case LBRACKET:
+		return "LBRACKET"
+	case RBRACKET:
+		return "RBRACKET"
+	case COMMA:
+		return "COMMA"
+	case INTERFACE:
+		return "INTERFACE"
+	case FN:
+		return "FN"
+	case RETURN:
+		return "RETURN"
+	case FOR:
+		return "FOR"
+	case BREAK:
+		return "BREAK"
+	case CONTINUE:
+		return "CONTINUE"
+	case SEMICOLON:
+		return "SEMICOLON"
+	case TYPE:
+		return "TYPE"
+	case STRUCT:
+		return "STRUCT"
+	case DOT:
+		return "DOT"
+	case IMPORT:
+		return "IMPORT"
 	default:
 		panic(fmt.Sprintf("unknown token kind: %d", k))
 	}
 }
 
+func (k TokenKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
 func (k TokenKind) GetBinaryPrecedence() int {
 	return GetBinaryOperatorPrecedence(k)
 }
@@ -206,6 +273,15 @@ type Token struct {
 	Val  string
 	len  int
 	Loc  TokenLocation
+	// Leading are the comments before the token, Trailing the comments after it on the same line
+	Leading  []Comment `json:",omitempty"`
+	Trailing []Comment `json:",omitempty"`
+}
+
+// Comment is a line comment like // text or a block comment like /* text */, Text includes the markers
+type Comment struct {
+	Text string
+	Loc  TokenLocation
 }
 
 func (t Token) String() string {
@@ -222,9 +298,15 @@ type Lexer struct {
 	len           int
 	line          int
 	startLine     int
-	tokens        chan Token
-	errors        chan Err
-	doneErr       chan bool
+	lastKind      TokenKind
+	// pending is the last scanned token, it is sent when the next token is scanned,
+	// so that the comments following it on its line can be attached to it
+	pending *Token
+	// leading are the comments scanned since the last token
+	leading []Comment
+	tokens  chan Token
+	errors  chan Err
+	doneErr chan bool
 }
 
 func NewLexer(name, input string) *Lexer {
@@ -290,9 +372,8 @@ func (l *Lexer) backup() {
 	l.offset -= l.len
 	if l.len == 1 && l.input[l.offset] == '\n' {
 		l.line--
-	} else {
-		l.lineCols[l.line] -= l.len
 	}
+	l.lineCols[l.line] -= l.len
 }
 
 func (l *Lexer) peek() rune {
@@ -301,6 +382,14 @@ func (l *Lexer) peek() rune {
 	return r
 }
 
+// peekByte returns the next byte without consuming it, or 0 at the end of input.
+func (l *Lexer) peekByte() byte {
+	if l.offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.offset]
+}
+
 // ignore ignores some input like whitespaces.
 func (l *Lexer) ignore() {
 	// l.line += strings.Count(l.input[l.startOffset:l.offset], "\n")
@@ -347,34 +436,71 @@ func (l *Lexer) acceptExact(valid string) bool {
 	return false
 }
 
+// errorf emits the scanned input as a token of the given kind and reports an error for it.
 func (l *Lexer) errorf(kind TokenKind, format string, args ...any) StateFn {
-	l.emit(kind)
-	l.errors <- Err{
+	err := Err{
 		Kind: Error,
-		Len:  l.len,
+		Len:  l.offset - l.startOffset,
 		Msg:  fmt.Sprintf(format, args...),
 		File: l.name,
 		Loc:  l.loc(),
 	}
-	l.next()
+	l.emit(kind)
+	l.errors <- err
 	return lexText
 }
 
 func (l *Lexer) emit(kind TokenKind) {
-	l.tokens <- Token{
-		Kind: kind,
-		Val:  l.input[l.startOffset:l.offset],
-		len:  l.offset - l.startOffset,
-		Loc:  l.loc(),
+	token := Token{
+		Kind:    kind,
+		Val:     l.input[l.startOffset:l.offset],
+		len:     l.offset - l.startOffset,
+		Loc:     l.loc(),
+		Leading: l.leading,
+	}
+	l.leading = nil
+	if l.pending != nil {
+		l.tokens <- *l.pending
+	}
+	l.pending = &token
+	// nothing follows the end of file
+	if kind == EOF {
+		l.tokens <- token
+		l.pending = nil
 	}
 
+	l.lastKind = kind
 	l.startOffset = l.offset
 	l.startLine = l.line
 	l.startLineCols[l.line] = l.lineCols[l.line]
 }
 
-func (l *Lexer) nextToken() Token {
-	return <-l.tokens
+// terminatesStatement reports whether a newline after the last emitted token ends the statement.
+// Like in Go, the newline is emitted as a SEMICOLON after tokens which can end a statement.
+func (l *Lexer) terminatesStatement() bool {
+	switch l.lastKind {
+	case IDENT, NUMBER, STRING, TRUE, FALSE, RPAREN, RBRACKET, RBRACE, RETURN, BREAK, CONTINUE,
+		INT, FLOAT, BOOL, STRING_TYPE, INTERFACE:
+		return true
+	}
+	return false
+}
+
+// Tokenize scans the whole input and returns the tokens including the EOF token and the lexical errors
+func Tokenize(name, input string) ([]Token, []Err) {
+	l := NewLexer(name, input)
+	tokens := []Token{}
+	errs := []Err{}
+	for {
+		select {
+		case token := <-l.tokens:
+			tokens = append(tokens, token)
+		case err := <-l.errors:
+			errs = append(errs, err)
+		case <-l.doneErr:
+			return tokens, errs
+		}
+	}
 }
 
 // State Functions
@@ -393,6 +519,8 @@ func lexText(l *Lexer) StateFn {
 		return nil
 	case isWhitespace(r):
 		return lexWhitespace
+	case strings.HasPrefix(l.input[l.offset:], "//") || strings.HasPrefix(l.input[l.offset:], "/*"):
+		return lexComment
 	case r == '(':
 		return lexLeftParen
 	case r == '+' || r == '-' || r == '*' || r == '/' || r == '%' || r == '!' || r == '<' ||
@@ -402,14 +530,27 @@ func lexText(l *Lexer) StateFn {
 		return lexRightParen
 	case r >= '0' && r <= '9':
 		return lexNumber
+	case r == '"':
+		return lexString
 	case r == '{':
 		return lexLeftBrace
 	case r == '}':
 		return lexRightBrace
+	case r == '[':
+		return lexLeftBracket
+	case r == ']':
+		return lexRightBracket
+	case r == ',':
+		return lexComma
+	case r == ';':
+		return lexSemicolon
+	case r == '.':
+		return lexDot
 	default:
 		if unicode.IsLetter(r) {
 			return lexIdentifierOrKeyword
 		}
+		l.next()
 		return l.errorf(BADTOKEN, "unrecognized character in input: %q", r)
 	}
 
@@ -427,6 +568,36 @@ func lexRightBrace(l *Lexer) StateFn {
 	return lexText
 }
 
+func lexLeftBracket(l *Lexer) StateFn {
+	l.next()
+	l.emit(LBRACKET)
+	return lexText
+}
+
+func lexRightBracket(l *Lexer) StateFn {
+	l.next()
+	l.emit(RBRACKET)
+	return lexText
+}
+
+func lexComma(l *Lexer) StateFn {
+	l.next()
+	l.emit(COMMA)
+	return lexText
+}
+
+func lexSemicolon(l *Lexer) StateFn {
+	l.next()
+	l.emit(SEMICOLON)
+	return lexText
+}
+
+func lexDot(l *Lexer) StateFn {
+	l.next()
+	l.emit(DOT)
+	return lexText
+}
+
 func lexIdentifierOrKeyword(l *Lexer) StateFn {
 	l.acceptRunFunc(unicode.IsLetter)
 	tok := l.input[l.startOffset:l.offset]
@@ -461,6 +632,61 @@ func lexIdentifierOrKeyword(l *Lexer) StateFn {
 		return lexText
 	}
 
+	if tok == "float" {
+		l.emit(FLOAT)
+		return lexText
+	}
+
+	if tok == "string" {
+		l.emit(STRING_TYPE)
+		return lexText
+	}
+
+	if tok == "interface" {
+		l.emit(INTERFACE)
+		return lexText
+	}
+
+	if tok == "fn" {
+		l.emit(FN)
+		return lexText
+	}
+
+	if tok == "return" {
+		l.emit(RETURN)
+		return lexText
+	}
+
+	if tok == "type" {
+		l.emit(TYPE)
+		return lexText
+	}
+
+	if tok == "struct" {
+		l.emit(STRUCT)
+		return lexText
+	}
+
+	if tok == "for" {
+		l.emit(FOR)
+		return lexText
+	}
+
+	if tok == "break" {
+		l.emit(BREAK)
+		return lexText
+	}
+
+	if tok == "continue" {
+		l.emit(CONTINUE)
+		return lexText
+	}
+
+	if tok == "import" {
+		l.emit(IMPORT)
+		return lexText
+	}
+
 	l.emit(IDENT)
 	return lexText
 }
@@ -477,12 +703,54 @@ func lexRightParen(l *Lexer) StateFn {
 	return lexText
 }
 
+// lexNumber scans integer and float literals like 42, 5.5, 1e9 and 1.5e-3.
+// The fraction part is only consumed if a digit follows the dot.
 func lexNumber(l *Lexer) StateFn {
-	l.acceptRun("0123456789")
+	digits := "0123456789"
+	l.acceptRun(digits)
+
+	if strings.HasPrefix(l.input[l.offset:], ".") && l.offset+1 < len(l.input) && isDigit(l.input[l.offset+1]) {
+		l.accept(".")
+		l.acceptRun(digits)
+	}
+
+	if l.accept("eE") {
+		l.accept("+-")
+		if !isDigit(l.peekByte()) {
+			return l.errorf(BADTOKEN, "malformed exponent in number literal: %q", l.input[l.startOffset:l.offset])
+		}
+		l.acceptRun(digits)
+	}
+
 	l.emit(NUMBER)
 	return lexText
 }
 
+// lexString scans a double-quoted string literal. Escape sequences are kept as is
+// in the token value, they are decoded by the parser.
+func lexString(l *Lexer) StateFn {
+	l.accept("\"")
+	for {
+		switch l.next() {
+		case '\\':
+			if r := l.next(); r == endOfFile || r == '\n' {
+				if r == '\n' {
+					l.backup()
+				}
+				return l.errorf(BADTOKEN, "unterminated string literal")
+			}
+		case '"':
+			l.emit(STRING)
+			return lexText
+		case '\n':
+			l.backup()
+			return l.errorf(BADTOKEN, "unterminated string literal")
+		case endOfFile:
+			return l.errorf(BADTOKEN, "unterminated string literal")
+		}
+	}
+}
+
 func lexOperator(l *Lexer) StateFn {
 
 	if l.accept(">") {
@@ -521,8 +789,8 @@ func lexOperator(l *Lexer) StateFn {
 			return lexText
 		}
 
-		// we don't handle yet, think about it later
-		l.backup()
+		l.emit(COLON)
+		return lexText
 	}
 
 	if l.accept("!") {
@@ -576,8 +844,54 @@ func lexOperator(l *Lexer) StateFn {
 	return lexText
 }
 
+// lexComment scans a line or block comment. A comment starting on the line of the previous token is
+// a trailing comment of that token, other comments lead the next token. Like in Go a block comment
+// spanning lines ends the statement as a newline does.
+func lexComment(l *Lexer) StateFn {
+	if l.acceptExact("//") {
+		for r := l.next(); r != '\n' && r != endOfFile; r = l.next() {
+		}
+		if l.len == 1 && l.input[l.offset-1] == '\n' {
+			l.backup()
+		}
+	} else {
+		l.acceptExact("/*")
+		for !l.acceptExact("*/") {
+			if l.next() == endOfFile {
+				return l.errorf(BADTOKEN, "unterminated block comment")
+			}
+		}
+	}
+
+	comment := Comment{Text: l.input[l.startOffset:l.offset], Loc: l.loc()}
+	if l.pending != nil && l.pending.Loc.Start.Line == comment.Loc.Start.Line {
+		l.pending.Trailing = append(l.pending.Trailing, comment)
+	} else {
+		l.leading = append(l.leading, comment)
+	}
+	l.ignore()
+
+	if comment.Loc.End.Line > comment.Loc.Start.Line && l.terminatesStatement() {
+		l.emit(SEMICOLON)
+	}
+	return lexText
+}
+
 func lexWhitespace(l *Lexer) StateFn {
-	l.acceptRun(Whitespace)
+	for {
+		r := l.next()
+		if r == '\n' && l.terminatesStatement() {
+			l.backup()
+			l.ignore()
+			l.next()
+			l.emit(SEMICOLON)
+			return lexText
+		}
+		if !isWhitespace(r) {
+			l.backup()
+			break
+		}
+	}
 	l.ignore()
 	return lexText
 }
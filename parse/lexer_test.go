@@ -0,0 +1,33 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenKindString(t *testing.T) {
+	for kind := EOF; kind <= IMPORT; kind++ {
+		if kind.String() == "" {
+			t.Errorf("token kind %d has no name", int(kind))
+		}
+	}
+}
+
+func TestOperatorAsOperand(t *testing.T) {
+	tests := map[string]string{
+		"x := && true": "expected expression got AND",
+		"ok := || a":   "expected expression got OR",
+		"y := & 1":     "expected expression got BITAND",
+		"y := | 1":     "expected expression got BITOR",
+	}
+	for src, want := range tests {
+		parser := NewParser("test.pd", src)
+		if _, err := parser.Parse(); err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		errs := parser.Errors.GetErrors()
+		if len(errs) == 0 || !strings.Contains(errs[0].Msg, want) {
+			t.Errorf("%q: got errors %v, want %q", src, errs, want)
+		}
+	}
+}
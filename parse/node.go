@@ -30,8 +30,60 @@ const (
 	NodeElseStatement
 	NodeBlockStatement
 	NodeVariableDeclaration
+	NodeString
+	NodeType
+	NodeArrayLiteral
+	NodeIndexExpression
+	NodeFunction
+	NodeReturnStatement
+	NodeForStatement
+	NodeBranchStatement
+	NodeTypeDeclaration
+	NodeStructLiteral
+	NodeSelectorExpression
+	NodeProgram
+	NodeBadExpression
+	NodeBadStatement
+	NodeImport
 )
 
+var nodeKindNames = map[NodeKind]string{
+	NodeEOF:                     "EOF",
+	NodeBinaryExpression:        "BinaryExpression",
+	NodeNumber:                  "Number",
+	NodeBoolean:                 "Boolean",
+	NodeParenthesisedExpression: "ParenthesisedExpression",
+	NodeUnaryExpression:         "UnaryExpression",
+	NodeAssignmentExpression:    "AssignmentExpression",
+	NodeCallExpression:          "CallExpression",
+	NodeIfStatement:             "IfStatement",
+	NodeElseStatement:           "ElseStatement",
+	NodeBlockStatement:          "BlockStatement",
+	NodeVariableDeclaration:     "VariableDeclaration",
+	NodeString:                  "String",
+	NodeType:                    "Type",
+	NodeArrayLiteral:            "ArrayLiteral",
+	NodeIndexExpression:         "IndexExpression",
+	NodeFunction:                "Function",
+	NodeReturnStatement:         "ReturnStatement",
+	NodeForStatement:            "ForStatement",
+	NodeBranchStatement:         "BranchStatement",
+	NodeTypeDeclaration:         "TypeDeclaration",
+	NodeStructLiteral:           "StructLiteral",
+	NodeSelectorExpression:      "SelectorExpression",
+	NodeProgram:                 "Program",
+	NodeBadExpression:           "BadExpression",
+	NodeBadStatement:            "BadStatement",
+	NodeImport:                  "Import",
+}
+
+func (k NodeKind) String() string {
+	if name, ok := nodeKindNames[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
 const (
 	NumberInt NumberKind = iota
 	NumberFloat
@@ -41,14 +93,16 @@ const (
 
 ///////////////////////////////////////////////////////////
 
-// NumberNode TODO implement float, complex and unsigned integers
+// NumberNode TODO implement complex and unsigned integers
 type NumberNode struct {
 	NodeKind
 	NumberKind
-	tr  *SyntaxTree
-	Pos int
-	Raw string
-	Int int64
+	tr    *SyntaxTree
+	Pos   int
+	Token Token
+	Raw   string
+	Int   int64
+	Float float64
 }
 
 func (n *NumberNode) Kind() NodeKind {
@@ -75,10 +129,11 @@ func (n *NumberNode) writeTo(builder *strings.Builder) {
 
 type BooleanNode struct {
 	NodeKind
-	tr  *SyntaxTree
-	Pos int
-	Raw string
-	Val bool
+	tr    *SyntaxTree
+	Pos   int
+	Token Token
+	Raw   string
+	Val   bool
 }
 
 func NewBooleanNode(tr *SyntaxTree, val bool, pos int) *BooleanNode {
@@ -113,6 +168,47 @@ func (n *BooleanNode) writeTo(builder *strings.Builder) {
 
 ///////////////////////////////////////////////////////////
 
+type StringNode struct {
+	NodeKind
+	tr    *SyntaxTree
+	Pos   int
+	Token Token
+	Raw   string
+	Val   string
+}
+
+func NewStringNode(tree *SyntaxTree, token Token, val string) *StringNode {
+	return &StringNode{
+		NodeKind: NodeString,
+		tr:       tree,
+		Token:    token,
+		Raw:      token.Val,
+		Val:      val,
+	}
+}
+
+func (n *StringNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *StringNode) String() string {
+	return n.Raw
+}
+
+func (n *StringNode) Position() int {
+	return n.Pos
+}
+
+func (n *StringNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *StringNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.Raw)
+}
+
+///////////////////////////////////////////////////////////
+
 type BinaryExpressionNode struct {
 	NodeKind
 	tr    *SyntaxTree
@@ -239,12 +335,15 @@ func (n *UnaryExpressionNode) writeTo(builder *strings.Builder) {
 
 ///////////////////////////////////////////////////////////
 
+// AssignmentExpressionNode assigns to a variable by Identifier or,
+// if Target is not nil, to an element like f[i] or a field like a.value.
 type AssignmentExpressionNode struct {
 	NodeKind
 	tr         *SyntaxTree
 	Pos        int
 	TypeToken  Token
 	Identifier Token
+	Target     Node
 	Op         Token
 	Right      Node
 }
@@ -260,11 +359,24 @@ func NewAssignmentExpressionNode(tree *SyntaxTree, typeToken Token, identifier T
 	}
 }
 
+func NewTargetAssignmentExpressionNode(tree *SyntaxTree, target Node, op Token, right Node) *AssignmentExpressionNode {
+	return &AssignmentExpressionNode{
+		NodeKind: NodeAssignmentExpression,
+		Target:   target,
+		Op:       op,
+		Right:    right,
+		tr:       tree,
+	}
+}
+
 func (n *AssignmentExpressionNode) Kind() NodeKind {
 	return n.NodeKind
 }
 
 func (n *AssignmentExpressionNode) String() string {
+	if n.Target != nil {
+		return n.Target.String() + n.Op.Val + n.Right.String()
+	}
 	return n.Identifier.Val + n.Op.Val + n.Right.String()
 }
 
@@ -277,18 +389,28 @@ func (n *AssignmentExpressionNode) tree() *SyntaxTree {
 }
 
 func (n *AssignmentExpressionNode) writeTo(builder *strings.Builder) {
-	builder.WriteString(n.Identifier.Val)
+	if n.Target != nil {
+		builder.WriteString(n.Target.String())
+	} else {
+		builder.WriteString(n.Identifier.Val)
+	}
 	builder.WriteString(n.Op.Val)
 	builder.WriteString(n.Right.String())
 }
 
 ///////////////////////////////////////////////////////////
 
+// CallExpressionNode is either an identifier access like a or,
+// if Callee is not nil, a function call like add(1, 2).
 type CallExpressionNode struct {
 	NodeKind
 	tr         *SyntaxTree
 	Pos        int
 	Identifier Token
+	Callee     Node
+	LParen     Token
+	Args       []Node
+	RParen     Token
 }
 
 func NewCallExpressionNode(tree *SyntaxTree, identifier Token) *CallExpressionNode {
@@ -299,12 +421,30 @@ func NewCallExpressionNode(tree *SyntaxTree, identifier Token) *CallExpressionNo
 	}
 }
 
+func NewCallNode(tree *SyntaxTree, callee Node, lParen Token, args []Node, rParen Token) *CallExpressionNode {
+	return &CallExpressionNode{
+		NodeKind: NodeCallExpression,
+		Callee:   callee,
+		LParen:   lParen,
+		Args:     args,
+		RParen:   rParen,
+		tr:       tree,
+	}
+}
+
+// IsCall reports whether the node calls a function or just accesses an identifier
+func (n *CallExpressionNode) IsCall() bool {
+	return n.Callee != nil
+}
+
 func (n *CallExpressionNode) Kind() NodeKind {
 	return n.NodeKind
 }
 
 func (n *CallExpressionNode) String() string {
-	return n.Identifier.Val
+	builder := strings.Builder{}
+	n.writeTo(&builder)
+	return builder.String()
 }
 
 func (n *CallExpressionNode) Position() int {
@@ -316,7 +456,20 @@ func (n *CallExpressionNode) tree() *SyntaxTree {
 }
 
 func (n *CallExpressionNode) writeTo(builder *strings.Builder) {
-	builder.WriteString(n.Identifier.Val)
+	if !n.IsCall() {
+		builder.WriteString(n.Identifier.Val)
+		return
+	}
+
+	builder.WriteString(n.Callee.String())
+	builder.WriteString(n.LParen.Val)
+	for i, arg := range n.Args {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(arg.String())
+	}
+	builder.WriteString(n.RParen.Val)
 }
 
 ///////////////////////////////////////////////////////////
@@ -460,22 +613,24 @@ type VariableDeclarationStatementNode struct {
 	tr           *SyntaxTree
 	Pos          int
 	TypeToken    Token
+	Type         *TypeNode
 	DeclareToken Token
 	HasTypeToken bool
 	Identifier   Token
 	Expression   Node
 }
 
-func NewVariableDeclarationNode(tree *SyntaxTree, typeToken *Token, identifier Token, declareToken Token, expression Node) *VariableDeclarationStatementNode {
+func NewVariableDeclarationNode(tree *SyntaxTree, typ *TypeNode, identifier Token, declareToken Token, expression Node) *VariableDeclarationStatementNode {
 
 	hasTypeToken := false
-	if typeToken != nil {
+	if typ != nil {
 		hasTypeToken = true
 	}
 
 	node := &VariableDeclarationStatementNode{
 		NodeKind:     NodeVariableDeclaration,
 		HasTypeToken: hasTypeToken,
+		Type:         typ,
 		Identifier:   identifier,
 		Expression:   expression,
 		DeclareToken: declareToken,
@@ -483,7 +638,7 @@ func NewVariableDeclarationNode(tree *SyntaxTree, typeToken *Token, identifier T
 	}
 
 	if hasTypeToken {
-		node.TypeToken = *typeToken
+		node.TypeToken = typ.BaseToken()
 	}
 
 	return node
@@ -494,7 +649,10 @@ func (n *VariableDeclarationStatementNode) Kind() NodeKind {
 }
 
 func (n *VariableDeclarationStatementNode) String() string {
-	return n.TypeToken.Val + n.Identifier.Val + n.Expression.String()
+	if n.HasTypeToken {
+		return n.Type.String() + n.Identifier.Val + n.Expression.String()
+	}
+	return n.Identifier.Val + n.Expression.String()
 }
 
 func (n *VariableDeclarationStatementNode) Position() int {
@@ -506,9 +664,771 @@ func (n *VariableDeclarationStatementNode) tree() *SyntaxTree {
 }
 
 func (n *VariableDeclarationStatementNode) writeTo(builder *strings.Builder) {
-	builder.WriteString(n.TypeToken.Val)
+	if n.HasTypeToken {
+		builder.WriteString(n.Type.String())
+	}
 	builder.WriteString(n.Identifier.Val)
 	builder.WriteString(n.Expression.String())
 }
 
 ///////////////////////////////////////////////////////////
+
+// TypeNode is a type annotation like int, float[], interface[][] or struct { int value }.
+// Array types have an Elem type and struct types have a Struct token, both have an empty Name.
+type TypeNode struct {
+	NodeKind
+	tr       *SyntaxTree
+	Pos      int
+	Name     Token
+	Elem     *TypeNode
+	LBracket Token
+	RBracket Token
+	Struct   Token
+	LBrace   Token
+	Fields   []Field
+	RBrace   Token
+}
+
+type Field struct {
+	Type *TypeNode
+	Name Token
+}
+
+func NewTypeNode(tree *SyntaxTree, name Token) *TypeNode {
+	return &TypeNode{
+		NodeKind: NodeType,
+		Name:     name,
+		tr:       tree,
+	}
+}
+
+func NewArrayTypeNode(tree *SyntaxTree, elem *TypeNode, lBracket Token, rBracket Token) *TypeNode {
+	return &TypeNode{
+		NodeKind: NodeType,
+		Elem:     elem,
+		LBracket: lBracket,
+		RBracket: rBracket,
+		tr:       tree,
+	}
+}
+
+func NewStructTypeNode(tree *SyntaxTree, structToken Token, lBrace Token, fields []Field, rBrace Token) *TypeNode {
+	return &TypeNode{
+		NodeKind: NodeType,
+		Struct:   structToken,
+		LBrace:   lBrace,
+		Fields:   fields,
+		RBrace:   rBrace,
+		tr:       tree,
+	}
+}
+
+// IsArray reports whether the type is an array type
+func (n *TypeNode) IsArray() bool {
+	return n.Elem != nil
+}
+
+// IsStruct reports whether the type is a struct type
+func (n *TypeNode) IsStruct() bool {
+	return n.Struct.Kind == STRUCT
+}
+
+// BaseToken returns the name token of the innermost element type
+func (n *TypeNode) BaseToken() Token {
+	if n.Elem != nil {
+		return n.Elem.BaseToken()
+	}
+	if n.IsStruct() {
+		return n.Struct
+	}
+	return n.Name
+}
+
+func (n *TypeNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *TypeNode) String() string {
+	if n.Elem != nil {
+		return n.Elem.String() + n.LBracket.Val + n.RBracket.Val
+	}
+	if n.IsStruct() {
+		builder := strings.Builder{}
+		builder.WriteString(n.Struct.Val)
+		builder.WriteString(" ")
+		builder.WriteString(n.LBrace.Val)
+		for i, field := range n.Fields {
+			if i > 0 {
+				builder.WriteString(";")
+			}
+			builder.WriteString(" ")
+			builder.WriteString(field.Type.String())
+			builder.WriteString(" ")
+			builder.WriteString(field.Name.Val)
+		}
+		builder.WriteString(" ")
+		builder.WriteString(n.RBrace.Val)
+		return builder.String()
+	}
+	return n.Name.Val
+}
+
+func (n *TypeNode) Position() int {
+	return n.Pos
+}
+
+func (n *TypeNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *TypeNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.String())
+}
+
+///////////////////////////////////////////////////////////
+
+type ArrayLiteralNode struct {
+	NodeKind
+	tr       *SyntaxTree
+	Pos      int
+	LBracket Token
+	Elements []Node
+	RBracket Token
+}
+
+func NewArrayLiteralNode(tree *SyntaxTree, lBracket Token, elements []Node, rBracket Token) *ArrayLiteralNode {
+	return &ArrayLiteralNode{
+		NodeKind: NodeArrayLiteral,
+		LBracket: lBracket,
+		Elements: elements,
+		RBracket: rBracket,
+		tr:       tree,
+	}
+}
+
+func (n *ArrayLiteralNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *ArrayLiteralNode) String() string {
+	builder := strings.Builder{}
+	n.writeTo(&builder)
+	return builder.String()
+}
+
+func (n *ArrayLiteralNode) Position() int {
+	return n.Pos
+}
+
+func (n *ArrayLiteralNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *ArrayLiteralNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.LBracket.Val)
+	for i, element := range n.Elements {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(element.String())
+	}
+	builder.WriteString(n.RBracket.Val)
+}
+
+///////////////////////////////////////////////////////////
+
+type IndexExpressionNode struct {
+	NodeKind
+	tr       *SyntaxTree
+	Pos      int
+	Left     Node
+	LBracket Token
+	Index    Node
+	RBracket Token
+}
+
+func NewIndexExpressionNode(tree *SyntaxTree, left Node, lBracket Token, index Node, rBracket Token) *IndexExpressionNode {
+	return &IndexExpressionNode{
+		NodeKind: NodeIndexExpression,
+		Left:     left,
+		LBracket: lBracket,
+		Index:    index,
+		RBracket: rBracket,
+		tr:       tree,
+	}
+}
+
+func (n *IndexExpressionNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *IndexExpressionNode) String() string {
+	return n.Left.String() + n.LBracket.Val + n.Index.String() + n.RBracket.Val
+}
+
+func (n *IndexExpressionNode) Position() int {
+	return n.Pos
+}
+
+func (n *IndexExpressionNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *IndexExpressionNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.Left.String())
+	builder.WriteString(n.LBracket.Val)
+	builder.WriteString(n.Index.String())
+	builder.WriteString(n.RBracket.Val)
+}
+
+///////////////////////////////////////////////////////////
+
+type Parameter struct {
+	Type *TypeNode
+	Name Token
+}
+
+// FunctionNode is a function declaration like fn add(int a, int b) int { ... }
+// or an anonymous function literal if Name is empty.
+type FunctionNode struct {
+	NodeKind
+	tr         *SyntaxTree
+	Pos        int
+	FnToken    Token
+	Name       Token
+	LParen     Token
+	Params     []Parameter
+	RParen     Token
+	ReturnType *TypeNode
+	Body       *BlockStatementNode
+}
+
+func NewFunctionNode(tree *SyntaxTree, fnToken Token, name Token, lParen Token, params []Parameter, rParen Token, returnType *TypeNode, body *BlockStatementNode) *FunctionNode {
+	return &FunctionNode{
+		NodeKind:   NodeFunction,
+		FnToken:    fnToken,
+		Name:       name,
+		LParen:     lParen,
+		Params:     params,
+		RParen:     rParen,
+		ReturnType: returnType,
+		Body:       body,
+		tr:         tree,
+	}
+}
+
+// Signature returns the function header like fn add(int a, int b) int
+func (n *FunctionNode) Signature() string {
+	builder := strings.Builder{}
+	builder.WriteString(n.FnToken.Val)
+	if n.Name.Val != "" {
+		builder.WriteString(" ")
+		builder.WriteString(n.Name.Val)
+	}
+	builder.WriteString("(")
+	for i, param := range n.Params {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(param.Type.String())
+		builder.WriteString(" ")
+		builder.WriteString(param.Name.Val)
+	}
+	builder.WriteString(")")
+	if n.ReturnType != nil {
+		builder.WriteString(" ")
+		builder.WriteString(n.ReturnType.String())
+	}
+	return builder.String()
+}
+
+func (n *FunctionNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *FunctionNode) String() string {
+	return n.Signature() + n.Body.String()
+}
+
+func (n *FunctionNode) Position() int {
+	return n.Pos
+}
+
+func (n *FunctionNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *FunctionNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.Signature())
+	n.Body.writeTo(builder)
+}
+
+///////////////////////////////////////////////////////////
+
+type ReturnStatementNode struct {
+	NodeKind
+	tr          *SyntaxTree
+	Pos         int
+	ReturnToken Token
+	Expression  Node
+}
+
+func NewReturnStatementNode(tree *SyntaxTree, returnToken Token, expression Node) *ReturnStatementNode {
+	return &ReturnStatementNode{
+		NodeKind:    NodeReturnStatement,
+		ReturnToken: returnToken,
+		Expression:  expression,
+		tr:          tree,
+	}
+}
+
+func (n *ReturnStatementNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *ReturnStatementNode) String() string {
+	if n.Expression == nil {
+		return n.ReturnToken.Val
+	}
+	return n.ReturnToken.Val + n.Expression.String()
+}
+
+func (n *ReturnStatementNode) Position() int {
+	return n.Pos
+}
+
+func (n *ReturnStatementNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *ReturnStatementNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.ReturnToken.Val)
+	if n.Expression != nil {
+		builder.WriteString(n.Expression.String())
+	}
+}
+
+///////////////////////////////////////////////////////////
+
+// ForStatementNode is a loop in one of the forms for { }, for cond { } and for init; cond; post { }.
+// Init, Condition and Post are nil when they are omitted.
+type ForStatementNode struct {
+	NodeKind
+	tr        *SyntaxTree
+	Pos       int
+	ForToken  Token
+	Init      Node
+	Condition Node
+	Post      Node
+	Body      Node
+}
+
+func NewForStatementNode(tree *SyntaxTree, forToken Token, init Node, condition Node, post Node, body Node) *ForStatementNode {
+	return &ForStatementNode{
+		NodeKind:  NodeForStatement,
+		ForToken:  forToken,
+		Init:      init,
+		Condition: condition,
+		Post:      post,
+		Body:      body,
+		tr:        tree,
+	}
+}
+
+func (n *ForStatementNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *ForStatementNode) String() string {
+	builder := strings.Builder{}
+	n.writeTo(&builder)
+	return builder.String()
+}
+
+func (n *ForStatementNode) Position() int {
+	return n.Pos
+}
+
+func (n *ForStatementNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *ForStatementNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.ForToken.Val)
+	if n.Init != nil || n.Post != nil {
+		if n.Init != nil {
+			builder.WriteString(n.Init.String())
+		}
+		builder.WriteString(";")
+		if n.Condition != nil {
+			builder.WriteString(n.Condition.String())
+		}
+		builder.WriteString(";")
+		if n.Post != nil {
+			builder.WriteString(n.Post.String())
+		}
+	} else if n.Condition != nil {
+		builder.WriteString(n.Condition.String())
+	}
+	builder.WriteString(n.Body.String())
+}
+
+///////////////////////////////////////////////////////////
+
+// BranchStatementNode is a break or continue statement
+type BranchStatementNode struct {
+	NodeKind
+	tr    *SyntaxTree
+	Pos   int
+	Token Token
+}
+
+func NewBranchStatementNode(tree *SyntaxTree, token Token) *BranchStatementNode {
+	return &BranchStatementNode{
+		NodeKind: NodeBranchStatement,
+		Token:    token,
+		tr:       tree,
+	}
+}
+
+func (n *BranchStatementNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *BranchStatementNode) String() string {
+	return n.Token.Val
+}
+
+func (n *BranchStatementNode) Position() int {
+	return n.Pos
+}
+
+func (n *BranchStatementNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *BranchStatementNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.Token.Val)
+}
+
+///////////////////////////////////////////////////////////
+
+// TypeDeclarationNode declares a type alias like type Meters = int or type abc = struct { int value }
+type TypeDeclarationNode struct {
+	NodeKind
+	tr          *SyntaxTree
+	Pos         int
+	TypeToken   Token
+	Name        Token
+	AssignToken Token
+	Type        *TypeNode
+}
+
+func NewTypeDeclarationNode(tree *SyntaxTree, typeToken Token, name Token, assignToken Token, typ *TypeNode) *TypeDeclarationNode {
+	return &TypeDeclarationNode{
+		NodeKind:    NodeTypeDeclaration,
+		TypeToken:   typeToken,
+		Name:        name,
+		AssignToken: assignToken,
+		Type:        typ,
+		tr:          tree,
+	}
+}
+
+func (n *TypeDeclarationNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *TypeDeclarationNode) String() string {
+	return n.TypeToken.Val + n.Name.Val + n.AssignToken.Val + n.Type.String()
+}
+
+func (n *TypeDeclarationNode) Position() int {
+	return n.Pos
+}
+
+func (n *TypeDeclarationNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *TypeDeclarationNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.TypeToken.Val)
+	builder.WriteString(n.Name.Val)
+	builder.WriteString(n.AssignToken.Val)
+	builder.WriteString(n.Type.String())
+}
+
+///////////////////////////////////////////////////////////
+
+type FieldValue struct {
+	Name  Token
+	Value Node
+}
+
+// StructLiteralNode constructs a struct value like abc{value: 5}
+type StructLiteralNode struct {
+	NodeKind
+	tr       *SyntaxTree
+	Pos      int
+	TypeName Token
+	LBrace   Token
+	Fields   []FieldValue
+	RBrace   Token
+}
+
+func NewStructLiteralNode(tree *SyntaxTree, typeName Token, lBrace Token, fields []FieldValue, rBrace Token) *StructLiteralNode {
+	return &StructLiteralNode{
+		NodeKind: NodeStructLiteral,
+		TypeName: typeName,
+		LBrace:   lBrace,
+		Fields:   fields,
+		RBrace:   rBrace,
+		tr:       tree,
+	}
+}
+
+func (n *StructLiteralNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *StructLiteralNode) String() string {
+	builder := strings.Builder{}
+	n.writeTo(&builder)
+	return builder.String()
+}
+
+func (n *StructLiteralNode) Position() int {
+	return n.Pos
+}
+
+func (n *StructLiteralNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *StructLiteralNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.TypeName.Val)
+	builder.WriteString(n.LBrace.Val)
+	for i, field := range n.Fields {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(field.Name.Val)
+		builder.WriteString(": ")
+		builder.WriteString(field.Value.String())
+	}
+	builder.WriteString(n.RBrace.Val)
+}
+
+///////////////////////////////////////////////////////////
+
+// SelectorExpressionNode accesses a struct field like a.value
+type SelectorExpressionNode struct {
+	NodeKind
+	tr    *SyntaxTree
+	Pos   int
+	Left  Node
+	Dot   Token
+	Field Token
+}
+
+func NewSelectorExpressionNode(tree *SyntaxTree, left Node, dot Token, field Token) *SelectorExpressionNode {
+	return &SelectorExpressionNode{
+		NodeKind: NodeSelectorExpression,
+		Left:     left,
+		Dot:      dot,
+		Field:    field,
+		tr:       tree,
+	}
+}
+
+func (n *SelectorExpressionNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *SelectorExpressionNode) String() string {
+	return n.Left.String() + n.Dot.Val + n.Field.Val
+}
+
+func (n *SelectorExpressionNode) Position() int {
+	return n.Pos
+}
+
+func (n *SelectorExpressionNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *SelectorExpressionNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.Left.String())
+	builder.WriteString(n.Dot.Val)
+	builder.WriteString(n.Field.Val)
+}
+
+///////////////////////////////////////////////////////////
+
+// ProgramNode is the root of a file, it holds the top level statements
+type ProgramNode struct {
+	NodeKind
+	tr    *SyntaxTree
+	Pos   int
+	Nodes []Node
+}
+
+func NewProgramNode(tree *SyntaxTree, nodes []Node) *ProgramNode {
+	return &ProgramNode{
+		NodeKind: NodeProgram,
+		Nodes:    nodes,
+		tr:       tree,
+	}
+}
+
+func (n *ProgramNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *ProgramNode) String() string {
+	builder := strings.Builder{}
+	n.writeTo(&builder)
+	return builder.String()
+}
+
+func (n *ProgramNode) Position() int {
+	return n.Pos
+}
+
+func (n *ProgramNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *ProgramNode) writeTo(builder *strings.Builder) {
+	for _, node := range n.Nodes {
+		builder.WriteString(node.String())
+		builder.WriteString("\n")
+	}
+}
+
+///////////////////////////////////////////////////////////
+
+// BadExpressionNode is a placeholder for a missing or invalid expression, Token is where the expression was expected
+type BadExpressionNode struct {
+	NodeKind
+	tr    *SyntaxTree
+	Pos   int
+	Token Token
+}
+
+func NewBadExpressionNode(tree *SyntaxTree, token Token) *BadExpressionNode {
+	return &BadExpressionNode{
+		NodeKind: NodeBadExpression,
+		Token:    token,
+		tr:       tree,
+	}
+}
+
+func (n *BadExpressionNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *BadExpressionNode) String() string {
+	return "<bad expression>"
+}
+
+func (n *BadExpressionNode) Position() int {
+	return n.Pos
+}
+
+func (n *BadExpressionNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *BadExpressionNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.String())
+}
+
+///////////////////////////////////////////////////////////
+
+// BadStatementNode is a placeholder for the tokens from From to To skipped by error recovery
+type BadStatementNode struct {
+	NodeKind
+	tr   *SyntaxTree
+	Pos  int
+	From Token
+	To   Token
+}
+
+func NewBadStatementNode(tree *SyntaxTree, from Token, to Token) *BadStatementNode {
+	return &BadStatementNode{
+		NodeKind: NodeBadStatement,
+		From:     from,
+		To:       to,
+		tr:       tree,
+	}
+}
+
+func (n *BadStatementNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *BadStatementNode) String() string {
+	return "<bad statement>"
+}
+
+func (n *BadStatementNode) Position() int {
+	return n.Pos
+}
+
+func (n *BadStatementNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *BadStatementNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.String())
+}
+
+///////////////////////////////////////////////////////////
+
+///////////////////////////////////////////////////////////
+
+// ImportNode imports the module at a path like import "lib/math", the module is bound to the last
+// element of the path
+type ImportNode struct {
+	NodeKind
+	tr          *SyntaxTree
+	Pos         int
+	ImportToken Token
+	Path        *StringNode
+}
+
+func NewImportNode(tree *SyntaxTree, importToken Token, path *StringNode) *ImportNode {
+	return &ImportNode{
+		NodeKind:    NodeImport,
+		ImportToken: importToken,
+		Path:        path,
+		tr:          tree,
+	}
+}
+
+// Name returns the name the module is bound to, the last element of the path without the .pd extension
+func (n *ImportNode) Name() string {
+	name := n.Path.Val[strings.LastIndex(n.Path.Val, "/")+1:]
+	return strings.TrimSuffix(name, ".pd")
+}
+
+func (n *ImportNode) Kind() NodeKind {
+	return n.NodeKind
+}
+
+func (n *ImportNode) String() string {
+	return n.ImportToken.Val + " " + n.Path.Token.Val
+}
+
+func (n *ImportNode) Position() int {
+	return n.Pos
+}
+
+func (n *ImportNode) tree() *SyntaxTree {
+	return n.tr
+}
+
+func (n *ImportNode) writeTo(builder *strings.Builder) {
+	builder.WriteString(n.String())
+}
@@ -2,11 +2,16 @@ package parse
 
 import (
 	"strconv"
+	"strings"
 	"sync"
 )
 
 type SyntaxTree struct {
+	// Name is the file name the tree is parsed from
+	Name string
 	Root Node
+	// Comments holds all comments of the source in order, they are also attached to the tokens as trivia
+	Comments []Comment
 }
 
 type Parser struct {
@@ -15,21 +20,33 @@ type Parser struct {
 	tokens    []Token
 	badTokens []Token
 	tree      *SyntaxTree
-	Errors    ErrorContainer
+	loopDepth int
+	// blockDepth is the number of blocks being parsed
+	blockDepth int
+	// noStructLiteral is set while parsing if and for headers where abc {} is the start of the body
+	noStructLiteral bool
+	// lexDone is closed when all errors of the lexer are collected
+	lexDone chan struct{}
+	Errors  ErrorContainer
+	// errorCount counts the reported and the dropped syntax errors, lastErrorLine is the line of the last one
+	errorCount    int
+	lastErrorLine int
 }
 
 func NewParser(name, input string) *Parser {
 	p := &Parser{
 		lexer: NewLexer(name, input),
-		tree:  &SyntaxTree{},
+		tree:  &SyntaxTree{Name: name},
 		Errors: ErrorContainer{
 			Errors: []Err{},
 			mu:     &sync.Mutex{},
 		},
 		badTokens: []Token{},
+		lexDone:   make(chan struct{}),
 	}
 
 	go func() {
+		defer close(p.lexDone)
 		// consume errors from lexer until it closes
 		for {
 			select {
@@ -68,13 +85,7 @@ func (p *Parser) expect2(kinds ...TokenKind) Token {
 		kindsStr += kind.String() + ", "
 	}
 
-	p.Errors.AddError(Err{
-		File: p.lexer.name,
-		Len:  p.currentToken().len,
-		Loc:  p.currentToken().Loc,
-		Msg:  "expected one of them <" + kindsStr + "> got <" + p.currentToken().Kind.String() + ">",
-		Kind: Error,
-	})
+	p.errorAt(p.currentToken(), "expected one of them <"+kindsStr+"> got <"+p.currentToken().Kind.String()+">")
 
 	return Token{
 		Kind: UNEXPECTED,
@@ -89,13 +100,7 @@ func (p *Parser) expect(kind TokenKind) Token {
 		return p.getCurrentAndNext()
 	}
 
-	p.Errors.AddError(Err{
-		File: p.lexer.name,
-		Len:  p.currentToken().len,
-		Loc:  p.currentToken().Loc,
-		Msg:  "expected " + kind.String() + " got " + p.currentToken().Kind.String(),
-		Kind: Error,
-	})
+	p.errorAt(p.currentToken(), "expected "+kind.String()+" got "+p.currentToken().Kind.String())
 
 	return Token{
 		Kind: UNEXPECTED,
@@ -109,7 +114,7 @@ func (p *Parser) expect(kind TokenKind) Token {
 func (p *Parser) currentToken() Token {
 
 	if p.pos >= len(p.tokens) {
-		p.tokens = append(p.tokens, <-p.lexer.tokens)
+		p.receive()
 	}
 
 	return p.tokens[p.pos]
@@ -117,15 +122,119 @@ func (p *Parser) currentToken() Token {
 
 func (p *Parser) peek(offset int) Token {
 	for len(p.tokens) <= p.pos+offset {
-		p.tokens = append(p.tokens, <-p.lexer.tokens)
+		p.receive()
 	}
 	return p.tokens[p.pos+offset]
 }
 
+// receive reads the next token from the lexer and collects its comments
+func (p *Parser) receive() {
+	token := <-p.lexer.tokens
+	p.tree.Comments = append(p.tree.Comments, token.Leading...)
+	p.tree.Comments = append(p.tree.Comments, token.Trailing...)
+	p.tokens = append(p.tokens, token)
+}
+
 func (p *Parser) Parse() (*SyntaxTree, error) {
-	t := &SyntaxTree{}
-	t.Root = p.parseStatement()
-	return t, nil
+	p.tree.Root = p.parseProgram()
+	// lexer errors are collected concurrently, wait for them before returning
+	<-p.lexDone
+	return p.tree, nil
+}
+
+// parseProgram parses statements until the end of file
+func (p *Parser) parseProgram() Node {
+	return NewProgramNode(p.tree, p.parseStatementList())
+}
+
+// parseStatementList parses statements until the closing brace of the block or the end of file.
+// After a statement with syntax errors the parser synchronizes on the end of the statement,
+// so that the next statement is parsed independently.
+func (p *Parser) parseStatementList() []Node {
+	statements := []Node{}
+	for {
+		kind := p.currentToken().Kind
+		if kind == EOF || kind == RBRACE && p.blockDepth > 0 {
+			return statements
+		}
+		if kind == SEMICOLON {
+			p.getCurrentAndNext()
+			continue
+		}
+
+		from, pos, errorCount := p.currentToken(), p.pos, p.errorCount
+		statement := p.parseStatement()
+		if p.errorCount == errorCount {
+			p.expectStatementEnd()
+		}
+
+		if p.errorCount > errorCount {
+			// tokens which can't start a statement like a stray ) are skipped
+			if p.pos == pos {
+				p.getCurrentAndNext()
+			}
+			p.synchronize()
+			if _, ok := statement.(*BadExpressionNode); ok {
+				statement = NewBadStatementNode(p.tree, from, p.tokens[p.pos-1])
+			}
+		}
+		statements = append(statements, statement)
+	}
+}
+
+// synchronize skips tokens up to the end of the current statement, a semicolon or newline, or up to
+// the closing brace of the enclosing block. Blocks in the skipped tokens are skipped as a whole.
+func (p *Parser) synchronize() {
+	depth := 0
+	for {
+		switch p.currentToken().Kind {
+		case EOF:
+			return
+		case LBRACE:
+			depth++
+		case RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case SEMICOLON:
+			if depth == 0 {
+				p.getCurrentAndNext()
+				return
+			}
+		}
+		p.getCurrentAndNext()
+	}
+}
+
+// errorAt reports a syntax error at the token. Errors on the line of the previous error are dropped,
+// they are mostly caused by the previous one.
+func (p *Parser) errorAt(token Token, msg string) {
+	line := token.Loc.Start.Line
+	p.errorCount++
+	if p.errorCount > 1 && line == p.lastErrorLine {
+		return
+	}
+	p.lastErrorLine = line
+
+	p.Errors.AddError(Err{
+		File: p.lexer.name,
+		Len:  token.len,
+		Loc:  token.Loc,
+		Msg:  msg,
+		Kind: Error,
+	})
+}
+
+// expectStatementEnd checks that the statement is terminated by a semicolon, a newline or the end of a block
+func (p *Parser) expectStatementEnd() {
+	switch p.currentToken().Kind {
+	case SEMICOLON:
+		p.getCurrentAndNext()
+	case RBRACE, EOF:
+	default:
+		p.errorAt(p.currentToken(), "expected ; or newline after statement got "+p.currentToken().Kind.String())
+	}
 }
 
 func (p *Parser) parseStatement() Node {
@@ -135,12 +244,31 @@ func (p *Parser) parseStatement() Node {
 	case LBRACE:
 		return p.parseBlockStatement()
 	case IDENT:
-		if p.peek(1).Kind == DECLARE {
+		if p.peek(1).Kind == DECLARE || p.peek(1).Kind == IDENT {
+			return p.parseVariableDeclaration()
+		}
+		// array of user defined type like abc[] a = ...
+		if p.peek(1).Kind == LBRACKET && p.peek(2).Kind == RBRACKET {
 			return p.parseVariableDeclaration()
 		}
 		return p.parseExpression()
-	case INT:
+	case TYPE:
+		return p.parseTypeDeclaration()
+	case IMPORT:
+		return p.parseImport()
+	case INT, FLOAT, BOOL, STRING_TYPE, INTERFACE:
 		return p.parseVariableDeclaration()
+	case FN:
+		if p.peek(1).Kind == IDENT {
+			return p.parseFunction()
+		}
+		return p.parseExpression()
+	case RETURN:
+		return p.parseReturnStatement()
+	case FOR:
+		return p.parseForStatement()
+	case BREAK, CONTINUE:
+		return p.parseBranchStatement()
 	case BADTOKEN:
 		p.badTokens = append(p.badTokens, p.getCurrentAndNext())
 		return p.parseStatement()
@@ -151,7 +279,10 @@ func (p *Parser) parseStatement() Node {
 
 func (p *Parser) parseIfStatement() Node {
 	ifToken := p.expect(IF)
+	noStructLiteral := p.noStructLiteral
+	p.noStructLiteral = true
 	condition := p.parseExpression()
+	p.noStructLiteral = noStructLiteral
 	body := p.parseStatement()
 	elseBody := p.parseElseStatement()
 
@@ -164,10 +295,10 @@ func (p *Parser) parseIfStatement() Node {
 }
 
 func (p *Parser) parseElseStatement() Node {
-	token := p.getCurrentAndNext()
-	if token.Kind != ELSE {
+	if p.currentToken().Kind != ELSE {
 		return nil
 	}
+	token := p.getCurrentAndNext()
 
 	// else if cases
 	if p.currentToken().Kind == IF {
@@ -179,23 +310,40 @@ func (p *Parser) parseElseStatement() Node {
 }
 
 func (p *Parser) parseBlockStatement() Node {
-	token := p.expect(LBRACE)
-	statements := []Node{}
-	for p.currentToken().Kind != RBRACE {
-		statements = append(statements, p.parseStatement())
+	// without its brace the block would take the following statements, the statement list
+	// synchronizes on the end of the statement instead
+	if p.currentToken().Kind != LBRACE {
+		token := p.currentToken()
+		p.expect(LBRACE)
+		return NewBadStatementNode(p.tree, token, token)
 	}
+	token := p.expect(LBRACE)
+	p.blockDepth++
+	statements := p.parseStatementList()
+	p.blockDepth--
 	return NewBlockStatementNode(p.tree, token, p.expect(RBRACE), statements)
 }
 
 func (p *Parser) parseExpression() Node {
-	if p.currentToken().Kind == IDENT {
-		switch p.peek(1).Kind {
-		case ASSIGN, PLUS_ASSIGN, MINUS_ASSIGN, MUL_ASSIGN, QUO_ASSIGN, REM_ASSIGN:
-			return p.parseAssignmentExpression()
+	if p.currentToken().Kind == IDENT && isAssignmentOperator(p.peek(1).Kind) {
+		return p.parseAssignmentExpression()
+	}
+
+	left := p.parseBinaryExpression(0)
+	if isAssignmentOperator(p.currentToken().Kind) {
+		switch left.(type) {
+		case *IndexExpressionNode, *SelectorExpressionNode:
+			opToken := p.getCurrentAndNext()
+			right := p.parseExpression()
+			return NewTargetAssignmentExpressionNode(p.tree, left, opToken, right)
+		case *BadExpressionNode:
+			return left
 		}
+
+		p.errorAt(p.currentToken(), "cannot assign to "+left.String())
 	}
 
-	return p.parseBinaryExpression(0)
+	return left
 }
 
 func (p *Parser) parseVariableDeclaration() Node {
@@ -206,12 +354,65 @@ func (p *Parser) parseVariableDeclaration() Node {
 		return NewVariableDeclarationNode(p.tree, nil, ident, declareToken, expr)
 	}
 
-	typeToken := p.expect2(INT, BOOL)
+	typ := p.parseType()
 	ident := p.expect(IDENT)
 	declareToken := p.expect(ASSIGN)
 	expr := p.parseExpression()
-	return NewVariableDeclarationNode(p.tree, &typeToken, ident, declareToken, expr)
+	return NewVariableDeclarationNode(p.tree, typ, ident, declareToken, expr)
+
+}
+
+// parseType parses a type annotation like int, bool[], interface[][], abc or struct { int value }
+func (p *Parser) parseType() *TypeNode {
+	var typ *TypeNode
+	if p.currentToken().Kind == STRUCT {
+		typ = p.parseStructType()
+	} else {
+		typ = NewTypeNode(p.tree, p.expect2(INT, FLOAT, BOOL, STRING_TYPE, INTERFACE, FN, IDENT))
+	}
+	for p.currentToken().Kind == LBRACKET && p.peek(1).Kind == RBRACKET {
+		typ = NewArrayTypeNode(p.tree, typ, p.expect(LBRACKET), p.expect(RBRACKET))
+	}
+	return typ
+}
 
+func (p *Parser) parseStructType() *TypeNode {
+	structToken := p.expect(STRUCT)
+	lBrace := p.expect(LBRACE)
+	fields := []Field{}
+	for p.currentToken().Kind != RBRACE && p.currentToken().Kind != EOF {
+		typ := p.parseType()
+		name := p.expect(IDENT)
+		if name.Kind == UNEXPECTED {
+			break
+		}
+		fields = append(fields, Field{Type: typ, Name: name})
+		// fields may optionally be separated by commas or semicolons
+		if p.currentToken().Kind == COMMA || p.currentToken().Kind == SEMICOLON {
+			p.getCurrentAndNext()
+		}
+	}
+	return NewStructTypeNode(p.tree, structToken, lBrace, fields, p.expect(RBRACE))
+}
+
+func (p *Parser) parseTypeDeclaration() Node {
+	typeToken := p.expect(TYPE)
+	name := p.expect(IDENT)
+	assignToken := p.expect(ASSIGN)
+	typ := p.parseType()
+	return NewTypeDeclarationNode(p.tree, typeToken, name, assignToken, typ)
+}
+
+func (p *Parser) parseImport() Node {
+	importToken := p.expect(IMPORT)
+	if p.blockDepth > 0 {
+		p.errorAt(importToken, "imports must be at the top level")
+	}
+	if p.currentToken().Kind != STRING {
+		p.errorAt(p.currentToken(), "expected import path got "+p.currentToken().Kind.String())
+		return NewBadStatementNode(p.tree, importToken, importToken)
+	}
+	return NewImportNode(p.tree, importToken, p.parseString().(*StringNode))
 }
 
 func (p *Parser) parseAssignmentExpression() Node {
@@ -249,55 +450,285 @@ func (p *Parser) parseBinaryExpression(parentPrecedence int) Node {
 }
 
 func (p *Parser) parsePrimary() Node {
+	operand := p.parseOperand()
+	if _, ok := operand.(*BadExpressionNode); ok {
+		return operand
+	}
+	for {
+		switch p.currentToken().Kind {
+		case LBRACKET:
+			operand = p.parseIndexExpression(operand)
+		case LPAREN:
+			operand = p.parseCall(operand)
+		case DOT:
+			operand = NewSelectorExpressionNode(p.tree, operand, p.expect(DOT), p.expect(IDENT))
+		default:
+			return operand
+		}
+	}
+}
+
+func (p *Parser) parseOperand() Node {
 	switch p.currentToken().Kind {
 	case NUMBER:
 		return p.parseNumber()
+	case STRING:
+		return p.parseString()
 	case LPAREN:
 		return p.parseParenthesizedExpression()
 	case FALSE, TRUE:
 		return p.parseBoolean()
 	case IDENT:
 		return p.parseIdentifierAccessOrCall()
+	case LBRACKET:
+		return p.parseArrayLiteral()
+	case FN:
+		// only statements declare functions, a name on a function literal would declare nothing
+		if p.peek(1).Kind == IDENT {
+			p.errorAt(p.peek(1), "function literal can't have a name")
+		}
+		return p.parseFunction()
 	}
 
-	return nil
+	token := p.currentToken()
+	p.errorAt(token, "expected expression got "+token.Kind.String())
+	return NewBadExpressionNode(p.tree, token)
+}
+
+func isBadExpression(node Node) bool {
+	_, ok := node.(*BadExpressionNode)
+	return ok
+}
+
+func (p *Parser) parseArrayLiteral() Node {
+	lBracket := p.expect(LBRACKET)
+	elements := []Node{}
+	for p.currentToken().Kind != RBRACKET && p.currentToken().Kind != EOF {
+		element := p.parseExpression()
+		if isBadExpression(element) {
+			break
+		}
+		elements = append(elements, element)
+		if p.currentToken().Kind != COMMA {
+			break
+		}
+		p.expect(COMMA)
+	}
+	return NewArrayLiteralNode(p.tree, lBracket, elements, p.expect(RBRACKET))
+}
+
+func (p *Parser) parseIndexExpression(left Node) Node {
+	lBracket := p.expect(LBRACKET)
+	index := p.parseExpression()
+	return NewIndexExpressionNode(p.tree, left, lBracket, index, p.expect(RBRACKET))
 }
 
 func (p *Parser) parseIdentifierAccessOrCall() Node {
 	ident := p.expect(IDENT)
 	if p.currentToken().Kind == LPAREN {
-		// return p.parseCall(ident)
+		return p.parseCall(NewCallExpressionNode(p.tree, ident))
+	}
+
+	if p.isStructLiteral() {
+		return p.parseStructLiteral(ident)
 	}
 
 	return NewCallExpressionNode(p.tree, ident)
 }
 
+// isStructLiteral reports whether the brace after a type name starts a struct literal like abc{value: 5}
+func (p *Parser) isStructLiteral() bool {
+	if p.currentToken().Kind != LBRACE {
+		return false
+	}
+	if p.peek(1).Kind == IDENT && p.peek(2).Kind == COLON {
+		return true
+	}
+	return p.peek(1).Kind == RBRACE && !p.noStructLiteral
+}
+
+func (p *Parser) parseStructLiteral(typeName Token) Node {
+	lBrace := p.expect(LBRACE)
+	fields := []FieldValue{}
+	for p.currentToken().Kind != RBRACE && p.currentToken().Kind != EOF {
+		name := p.expect(IDENT)
+		p.expect(COLON)
+		value := p.parseExpression()
+		if name.Kind == UNEXPECTED || isBadExpression(value) {
+			break
+		}
+		fields = append(fields, FieldValue{Name: name, Value: value})
+		if p.currentToken().Kind != COMMA {
+			break
+		}
+		p.expect(COMMA)
+	}
+	return NewStructLiteralNode(p.tree, typeName, lBrace, fields, p.expect(RBRACE))
+}
+
+func (p *Parser) parseCall(callee Node) Node {
+	lParen := p.expect(LPAREN)
+	args := []Node{}
+	for p.currentToken().Kind != RPAREN && p.currentToken().Kind != EOF {
+		arg := p.parseExpression()
+		if isBadExpression(arg) {
+			break
+		}
+		args = append(args, arg)
+		if p.currentToken().Kind != COMMA {
+			break
+		}
+		p.expect(COMMA)
+	}
+	return NewCallNode(p.tree, callee, lParen, args, p.expect(RPAREN))
+}
+
+// parseFunction parses both function declarations and anonymous function literals
+func (p *Parser) parseFunction() Node {
+	fnToken := p.expect(FN)
+	name := emptyToken
+	if p.currentToken().Kind == IDENT {
+		name = p.expect(IDENT)
+	}
+
+	lParen := p.expect(LPAREN)
+	params := []Parameter{}
+	for p.currentToken().Kind != RPAREN && p.currentToken().Kind != EOF {
+		typ := p.parseType()
+		params = append(params, Parameter{Type: typ, Name: p.expect(IDENT)})
+		if p.currentToken().Kind != COMMA {
+			break
+		}
+		p.expect(COMMA)
+	}
+	rParen := p.expect(RPAREN)
+
+	var returnType *TypeNode
+	if p.currentToken().Kind != LBRACE {
+		returnType = p.parseType()
+	}
+
+	// break and continue can't jump out of the function body
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	body, ok := p.parseBlockStatement().(*BlockStatementNode)
+	p.loopDepth = loopDepth
+	if !ok {
+		return NewBadExpressionNode(p.tree, fnToken)
+	}
+	return NewFunctionNode(p.tree, fnToken, name, lParen, params, rParen, returnType, body)
+}
+
+// isStatement reports whether the node parsed by parseStatement is a statement rather than an expression
+func isStatement(node Node) bool {
+	switch n := node.(type) {
+	case *VariableDeclarationStatementNode, *IfStatementNode, *ReturnStatementNode, *ForStatementNode,
+		*BranchStatementNode, *TypeDeclarationNode, *ImportNode:
+		return true
+	case *FunctionNode:
+		return n.Name.Val != ""
+	}
+	return false
+}
+
+func (p *Parser) parseForStatement() Node {
+	forToken := p.expect(FOR)
+	var init, condition, post Node
+
+	noStructLiteral := p.noStructLiteral
+	p.noStructLiteral = true
+
+	if p.currentToken().Kind != LBRACE {
+		if p.currentToken().Kind != SEMICOLON {
+			init = p.parseStatement()
+		}
+
+		if p.currentToken().Kind == SEMICOLON {
+			p.expect(SEMICOLON)
+			if p.currentToken().Kind != SEMICOLON {
+				condition = p.parseExpression()
+			}
+			p.expect(SEMICOLON)
+			if p.currentToken().Kind != LBRACE {
+				post = p.parseExpression()
+			}
+		} else {
+			// for cond { } form
+			condition, init = init, nil
+			switch {
+			case isStatement(condition) && p.currentToken().Kind == LBRACE:
+				p.errorAt(FirstToken(condition), "expected for loop condition got statement")
+			case isStatement(condition):
+				// the init statement of the three clause form
+				p.expect(SEMICOLON)
+			}
+		}
+	}
+	p.noStructLiteral = noStructLiteral
+
+	p.loopDepth++
+	body := p.parseBlockStatement()
+	p.loopDepth--
+
+	return NewForStatementNode(p.tree, forToken, init, condition, post, body)
+}
+
+func (p *Parser) parseBranchStatement() Node {
+	token := p.expect2(BREAK, CONTINUE)
+	if p.loopDepth == 0 {
+		p.errorAt(token, token.Val+" is not in a loop")
+	}
+	return NewBranchStatementNode(p.tree, token)
+}
+
+func (p *Parser) parseReturnStatement() Node {
+	returnToken := p.expect(RETURN)
+	switch p.currentToken().Kind {
+	case RBRACE, SEMICOLON, EOF:
+		return NewReturnStatementNode(p.tree, returnToken, nil)
+	}
+	return NewReturnStatementNode(p.tree, returnToken, p.parseExpression())
+}
+
 func (p *Parser) parseParenthesizedExpression() Node {
 	openParenthesisToken := p.expect(LPAREN)
+	noStructLiteral := p.noStructLiteral
+	p.noStructLiteral = false
 	expr := p.parseBinaryExpression(0)
-	if p.currentToken().Kind != RPAREN {
-		return nil
-	}
+	p.noStructLiteral = noStructLiteral
 	closeParenthesisToken := p.expect(RPAREN)
 	return NewParenthesizedExpressionNode(p.tree, openParenthesisToken, expr, closeParenthesisToken)
 }
 
 func (p *Parser) parseNumber() Node {
 	val := p.expect(NUMBER)
-	valInt, err := strconv.Atoi(val.Val)
+
+	if strings.ContainsAny(val.Val, ".eE") {
+		valFloat, err := strconv.ParseFloat(val.Val, 64)
+		if err != nil {
+			p.errorAt(val, "Unable to parse float: "+val.Val)
+		}
+
+		return &NumberNode{
+			NodeKind:   NodeNumber,
+			NumberKind: NumberFloat,
+			tr:         p.tree,
+			Token:      val,
+			Raw:        val.Val,
+			Float:      valFloat,
+		}
+	}
+
+	valInt, err := strconv.ParseInt(val.Val, 10, 64)
 
 	if err != nil {
-		p.Errors.AddError(Err{
-			File: p.lexer.name,
-			Len:  val.len,
-			Loc:  val.Loc,
-			Msg:  "Unable to parse number: " + val.Val,
-			Kind: Error,
-		})
+		p.errorAt(val, "Unable to parse number: "+val.Val)
 
 		return &NumberNode{
 			NodeKind:   NodeNumber,
 			NumberKind: NumberInt,
+			tr:         p.tree,
+			Token:      val,
 			Raw:        val.Val,
 		}
 	}
@@ -305,9 +736,22 @@ func (p *Parser) parseNumber() Node {
 	return &NumberNode{
 		NodeKind:   NodeNumber,
 		NumberKind: NumberInt,
+		tr:         p.tree,
+		Token:      val,
 		Raw:        val.Val,
-		Int:        int64(valInt),
+		Int:        valInt,
+	}
+}
+
+func (p *Parser) parseString() Node {
+	val := p.expect(STRING)
+	str, err := unquoteString(val.Val)
+
+	if err != nil {
+		p.errorAt(val, "Unable to parse string: "+err.Error())
 	}
+
+	return NewStringNode(p.tree, val, str)
 }
 
 func (p *Parser) parseBoolean() Node {
@@ -315,22 +759,20 @@ func (p *Parser) parseBoolean() Node {
 	valBool, err := strconv.ParseBool(val.Val)
 
 	if err != nil {
-		p.Errors.AddError(Err{
-			File: p.lexer.name,
-			Len:  val.len,
-			Loc:  val.Loc,
-			Msg:  "Unable to parse boolean: " + val.Val,
-			Kind: Error,
-		})
+		p.errorAt(val, "Unable to parse boolean: "+val.Val)
 
 		return &BooleanNode{
 			NodeKind: NodeBoolean,
+			tr:       p.tree,
+			Token:    val,
 			Raw:      val.Val,
 		}
 	}
 
 	return &BooleanNode{
 		NodeKind: NodeBoolean,
+		tr:       p.tree,
+		Token:    val,
 		Raw:      val.Val,
 		Val:      valBool,
 	}
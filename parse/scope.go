@@ -1,13 +1,19 @@
 package parse
 
+import "sort"
+
 type Scope struct {
 	variables map[string]any
-	outer     *Scope
+	types     map[string]any
+	// declared holds the types of the variables declared with one, it is created on first use
+	declared map[string]any
+	outer    *Scope
 }
 
 func NewScope(outer *Scope) *Scope {
 	return &Scope{
 		variables: make(map[string]any),
+		types:     make(map[string]any),
 		outer:     outer,
 	}
 }
@@ -27,8 +33,79 @@ func (s *Scope) ResolveLocal(name string) (any, bool) {
 
 func (s *Scope) Define(name string, val any) {
 	s.variables[name] = val
+	delete(s.declared, name)
+}
+
+// DefineTyped defines a variable together with the type it is declared with
+func (s *Scope) DefineTyped(name string, val, typ any) {
+	s.variables[name] = val
+	if s.declared == nil {
+		s.declared = make(map[string]any)
+	}
+	s.declared[name] = typ
+}
+
+// DeclaredType returns the declared type of the variable in the nearest scope that defines it,
+// ok is false if the variable is not defined or was defined without a type
+func (s *Scope) DeclaredType(name string) (any, bool) {
+	for scope := s; scope != nil; scope = scope.outer {
+		if _, ok := scope.variables[name]; ok {
+			typ, ok := scope.declared[name]
+			return typ, ok
+		}
+	}
+	return nil, false
+}
+
+// Assign updates the variable in the nearest scope that defines it.
+// It returns false if the variable is not defined in the scope chain.
+func (s *Scope) Assign(name string, val any) bool {
+	for scope := s; scope != nil; scope = scope.outer {
+		if _, ok := scope.variables[name]; ok {
+			scope.variables[name] = val
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveType looks up a type declared with the type keyword, types live in their own namespace
+func (s *Scope) ResolveType(name string) (any, bool) {
+	typ, ok := s.types[name]
+	if !ok && s.outer != nil {
+		return s.outer.ResolveType(name)
+	}
+	return typ, ok
+}
+
+func (s *Scope) ResolveLocalType(name string) (any, bool) {
+	typ, ok := s.types[name]
+	return typ, ok
+}
+
+func (s *Scope) DefineType(name string, typ any) {
+	s.types[name] = typ
 }
 
 func (s *Scope) Parent() *Scope {
 	return s.outer
 }
+
+// Names returns the sorted names of the variables defined in this scope
+func (s *Scope) Names() []string {
+	return sortedKeys(s.variables)
+}
+
+// TypeNames returns the sorted names of the types declared in this scope
+func (s *Scope) TypeNames() []string {
+	return sortedKeys(s.types)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,153 @@
+package parse
+
+// FirstToken returns the first token of the node, it is the zero token for nil and empty programs
+func FirstToken(node Node) Token {
+	switch n := node.(type) {
+	case *NumberNode:
+		return n.Token
+	case *BooleanNode:
+		return n.Token
+	case *StringNode:
+		return n.Token
+	case *BinaryExpressionNode:
+		return FirstToken(n.Left)
+	case *ParenthesisedExpressionNode:
+		return n.Left
+	case *UnaryExpressionNode:
+		return n.Op
+	case *AssignmentExpressionNode:
+		if n.Target != nil {
+			return FirstToken(n.Target)
+		}
+		return n.Identifier
+	case *CallExpressionNode:
+		if n.IsCall() {
+			return FirstToken(n.Callee)
+		}
+		return n.Identifier
+	case *BlockStatementNode:
+		return n.Left
+	case *IfStatementNode:
+		return n.IfToken
+	case *ElseStatementNode:
+		return n.Else
+	case *VariableDeclarationStatementNode:
+		if n.HasTypeToken {
+			return FirstToken(n.Type)
+		}
+		return n.Identifier
+	case *TypeNode:
+		if n.Elem != nil {
+			return FirstToken(n.Elem)
+		}
+		if n.IsStruct() {
+			return n.Struct
+		}
+		return n.Name
+	case *ArrayLiteralNode:
+		return n.LBracket
+	case *IndexExpressionNode:
+		return FirstToken(n.Left)
+	case *FunctionNode:
+		return n.FnToken
+	case *ReturnStatementNode:
+		return n.ReturnToken
+	case *ForStatementNode:
+		return n.ForToken
+	case *BranchStatementNode:
+		return n.Token
+	case *TypeDeclarationNode:
+		return n.TypeToken
+	case *StructLiteralNode:
+		return n.TypeName
+	case *SelectorExpressionNode:
+		return FirstToken(n.Left)
+	case *ProgramNode:
+		if len(n.Nodes) > 0 {
+			return FirstToken(n.Nodes[0])
+		}
+	case *BadExpressionNode:
+		return n.Token
+	case *BadStatementNode:
+		return n.From
+	case *ImportNode:
+		return n.ImportToken
+	}
+	return Token{}
+}
+
+// LastToken returns the last token of the node, it is the zero token for nil and empty programs
+func LastToken(node Node) Token {
+	switch n := node.(type) {
+	case *NumberNode:
+		return n.Token
+	case *BooleanNode:
+		return n.Token
+	case *StringNode:
+		return n.Token
+	case *BinaryExpressionNode:
+		return LastToken(n.Right)
+	case *ParenthesisedExpressionNode:
+		return n.Right
+	case *UnaryExpressionNode:
+		return LastToken(n.Right)
+	case *AssignmentExpressionNode:
+		return LastToken(n.Right)
+	case *CallExpressionNode:
+		if n.IsCall() {
+			return n.RParen
+		}
+		return n.Identifier
+	case *BlockStatementNode:
+		return n.Right
+	case *IfStatementNode:
+		if n.Else != nil {
+			return LastToken(n.Else)
+		}
+		return LastToken(n.Body)
+	case *ElseStatementNode:
+		return LastToken(n.Body)
+	case *VariableDeclarationStatementNode:
+		return LastToken(n.Expression)
+	case *TypeNode:
+		if n.Elem != nil {
+			return n.RBracket
+		}
+		if n.IsStruct() {
+			return n.RBrace
+		}
+		return n.Name
+	case *ArrayLiteralNode:
+		return n.RBracket
+	case *IndexExpressionNode:
+		return n.RBracket
+	case *FunctionNode:
+		return n.Body.Right
+	case *ReturnStatementNode:
+		if n.Expression != nil {
+			return LastToken(n.Expression)
+		}
+		return n.ReturnToken
+	case *ForStatementNode:
+		return LastToken(n.Body)
+	case *BranchStatementNode:
+		return n.Token
+	case *TypeDeclarationNode:
+		return LastToken(n.Type)
+	case *StructLiteralNode:
+		return n.RBrace
+	case *SelectorExpressionNode:
+		return n.Field
+	case *ProgramNode:
+		if len(n.Nodes) > 0 {
+			return LastToken(n.Nodes[len(n.Nodes)-1])
+		}
+	case *BadExpressionNode:
+		return n.Token
+	case *BadStatementNode:
+		return n.To
+	case *ImportNode:
+		return n.Path.Token
+	}
+	return Token{}
+}
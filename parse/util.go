@@ -1,5 +1,12 @@
 package parse
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
 const Whitespace = " \t\r\n"
 
 func GetUnaryOperatorPrecedence(kind TokenKind) int {
@@ -26,6 +33,32 @@ func GetBinaryOperatorPrecedence(kind TokenKind) int {
 	return 0
 }
 
+func isAssignmentOperator(kind TokenKind) bool {
+	switch kind {
+	case ASSIGN, PLUS_ASSIGN, MINUS_ASSIGN, MUL_ASSIGN, QUO_ASSIGN, REM_ASSIGN:
+		return true
+	}
+	return false
+}
+
+// CompoundAssignOperator converts compound assignment operator like += to the underlying binary operator
+func CompoundAssignOperator(op Token) Token {
+	switch op.Kind {
+	case PLUS_ASSIGN:
+		op.Kind = PLUS
+	case MINUS_ASSIGN:
+		op.Kind = MINUS
+	case MUL_ASSIGN:
+		op.Kind = MUL
+	case QUO_ASSIGN:
+		op.Kind = QUO
+	case REM_ASSIGN:
+		op.Kind = REM
+	}
+	op.Val = strings.TrimSuffix(op.Val, "=")
+	return op
+}
+
 func isDigit(c byte) bool {
 	return c >= '0' && c <= '9'
 }
@@ -40,3 +73,55 @@ func getMapValueOrDefineAndGetValue(m map[int]int, key int) interface{} {
 	}
 	return m[key]
 }
+
+// unquoteString decodes a double-quoted string literal.
+// Supported escape sequences are \n, \t, \r, \", \\ and \u{XXXX}.
+func unquoteString(raw string) (string, error) {
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return "", fmt.Errorf("invalid string literal %s", raw)
+	}
+
+	body := raw[1 : len(raw)-1]
+	var builder strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			builder.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(body) {
+			return "", fmt.Errorf("unterminated escape sequence")
+		}
+
+		switch body[i] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 't':
+			builder.WriteByte('\t')
+		case 'r':
+			builder.WriteByte('\r')
+		case '"':
+			builder.WriteByte('"')
+		case '\\':
+			builder.WriteByte('\\')
+		case 'u':
+			end := strings.IndexByte(body[i:], '}')
+			if i+1 >= len(body) || body[i+1] != '{' || end < 0 {
+				return "", fmt.Errorf("malformed unicode escape, expected \\u{XXXX}")
+			}
+			hex := body[i+2 : i+end]
+			code, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil || hex == "" || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid unicode code point \\u{%s}", hex)
+			}
+			builder.WriteRune(rune(code))
+			i += end
+		default:
+			return "", fmt.Errorf("unknown escape sequence \\%c", body[i])
+		}
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"myProgrammingLanguage/check"
+	"myProgrammingLanguage/eval"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const replHelp = `Enter palm code to run it, input with unclosed braces continues on the next line.
+
+	:type expr     print the static type of the expression
+	:ast code      print the syntax tree of the code
+	:tokens [code] print the tokens of the code or of the last input
+	:vars          print the variables and types of the session
+	:load file     run a file in the session
+	:reset         forget all variables and types
+	:history       print the input history
+	:help          print this help
+	:quit          leave the repl, like exit or end of input
+`
+
+// session is the state of an interactive session. All inputs are run in the same global scope,
+// the checker knows the types of its variables.
+type session struct {
+	engine     eval.Engine
+	searchPath []string
+	scope      *parse.Scope
+	checker    *check.Checker
+	// imports runs the inputs, it shares the loader of imported modules with the checker
+	imports  *eval.Importer
+	renderer *parse.Renderer
+	out      io.Writer
+	// inputs counts the inputs, they are named after their number in diagnostics
+	inputs int
+	last   string
+	// history holds the previous inputs, it is appended to historyFile
+	history     []string
+	historyFile string
+}
+
+func newSession(engine eval.Engine, searchPath []string, out io.Writer, color bool) *session {
+	s := &session{engine: engine, searchPath: searchPath, out: out, renderer: parse.NewRenderer(color)}
+	s.reset()
+	return s
+}
+
+// reset forgets the definitions and the imported modules, so that changed modules are loaded again
+func (s *session) reset() {
+	loader := module.NewLoader(s.searchPath)
+	s.scope = eval.NewGlobalScope()
+	s.checker = check.NewChecker(parse.NewErrorContainer())
+	s.checker.Loader = loader
+	s.imports = eval.NewImporter(loader, s.engine, eval.Limits{})
+}
+
+// historyPath returns the file the history is kept in, $PALM_HISTORY or .palm_history in the home directory
+func historyPath() string {
+	if path := os.Getenv("PALM_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".palm_history")
+}
+
+// maxHistory is the number of history lines loaded at start
+const maxHistory = 1000
+
+func (s *session) loadHistory(path string) {
+	s.historyFile = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+	}
+	s.history = lines
+}
+
+// addHistory appends the lines of an input to the history and the history file
+func (s *session) addHistory(input string) {
+	lines := strings.Split(strings.TrimRight(input, "\n"), "\n")
+	s.history = append(s.history, lines...)
+	if s.historyFile == "" {
+		return
+	}
+
+	f, err := os.OpenFile(s.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, strings.Join(lines, "\n"))
+}
+
+// run reads inputs from in until the end of input or :quit
+func (s *session) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	input := ""
+	for {
+		if input == "" {
+			fmt.Fprint(s.out, ">> ")
+		} else {
+			fmt.Fprint(s.out, ".. ")
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out, "Bye!")
+			return
+		}
+
+		input += scanner.Text() + "\n"
+		if incomplete(input) {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(input)
+		input = ""
+		if trimmed == "" {
+			continue
+		}
+		s.addHistory(trimmed)
+		if trimmed == "exit" || trimmed == ":quit" {
+			fmt.Fprintln(s.out, "Bye!")
+			return
+		}
+
+		if strings.HasPrefix(trimmed, ":") {
+			s.command(trimmed)
+		} else {
+			s.eval(s.nextName(), trimmed)
+		}
+	}
+}
+
+// incomplete reports whether src has unclosed braces, parentheses or brackets
+func incomplete(src string) bool {
+	tokens, _ := parse.Tokenize("", src)
+	depth := 0
+	for _, token := range tokens {
+		switch token.Kind {
+		case parse.LBRACE, parse.LPAREN, parse.LBRACKET:
+			depth++
+		case parse.RBRACE, parse.RPAREN, parse.RBRACKET:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func (s *session) nextName() string {
+	s.inputs++
+	return fmt.Sprintf("<repl %d>", s.inputs)
+}
+
+func (s *session) command(input string) {
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case ":type":
+		s.printType(arg)
+	case ":ast":
+		if tree, ok := s.parse(s.nextName(), arg); ok {
+			parse.Fprint(s.out, tree.Root)
+		}
+	case ":tokens":
+		if arg == "" {
+			arg = s.last
+		}
+		tokens, errs := parse.Tokenize("<tokens>", arg)
+		for _, token := range tokens {
+			start := token.Loc.Start
+			fmt.Fprintf(s.out, "%d:%d\t%s\t%q\n", start.Line+1, start.Col+1, token.Kind, token.Val)
+		}
+		s.renderer.AddSource("<tokens>", arg)
+		s.renderer.RenderAll(s.out, errs)
+	case ":vars":
+		s.printVars()
+	case ":load":
+		src, err := os.ReadFile(arg)
+		if err != nil {
+			fmt.Fprintln(s.out, err)
+			return
+		}
+		s.eval(arg, string(src))
+	case ":reset":
+		s.reset()
+		fmt.Fprintln(s.out, "session reset")
+	case ":history":
+		for i, line := range s.history {
+			fmt.Fprintf(s.out, "%5d  %s\n", i+1, line)
+		}
+	case ":help":
+		fmt.Fprint(s.out, replHelp)
+	default:
+		fmt.Fprintf(s.out, "unknown command %s, :help lists the commands\n", name)
+	}
+}
+
+// parse parses the source and renders its syntax errors
+func (s *session) parse(name, src string) (*parse.SyntaxTree, bool) {
+	s.renderer.AddSource(name, src)
+	parser := parse.NewParser(name, src)
+	tree, err := parser.Parse()
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return nil, false
+	}
+	if parser.Errors.HasErrors() {
+		s.renderer.RenderAll(s.out, parser.Errors.GetErrors())
+		return nil, false
+	}
+	return tree, true
+}
+
+// typeErrors checks the tree and renders the type errors, it returns false if there are errors
+func (s *session) typeErrors(tree *parse.SyntaxTree, local bool) bool {
+	s.checker.Errors.Clear()
+	if local {
+		s.checker.CheckLocal(tree)
+	} else {
+		s.checker.Check(tree)
+	}
+	if s.checker.Errors.HasErrors() {
+		for _, file := range s.checker.Loader.Files() {
+			s.renderer.AddSource(file.Path, file.Src)
+		}
+		s.renderer.RenderAll(s.out, s.checker.Errors.GetErrors())
+		return false
+	}
+	return true
+}
+
+// eval checks and runs the source and prints its value. The input is checked in a nested scope first,
+// so that the definitions of input with type errors aren't kept by the checker.
+func (s *session) eval(name, src string) {
+	s.last = src
+	tree, ok := s.parse(name, src)
+	if !ok || !s.typeErrors(tree, true) || !s.typeErrors(tree, false) {
+		return
+	}
+
+	// interrupting stops the running code instead of the repl
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := s.imports.Run(ctx, name, tree, s.scope)
+	if err != nil {
+		if runtimeErr, ok := err.(*eval.RuntimeError); ok {
+			s.renderer.Render(s.out, runtimeErr.Diagnostic())
+			if len(runtimeErr.Stack) > 1 {
+				fmt.Fprint(s.out, runtimeErr.StackTrace())
+			}
+		} else {
+			fmt.Fprintln(s.out, err)
+		}
+		return
+	}
+	if result != nil {
+		fmt.Fprintln(s.out, result)
+	}
+}
+
+func (s *session) printType(src string) {
+	tree, ok := s.parse(s.nextName(), src)
+	if !ok || !s.typeErrors(tree, true) {
+		return
+	}
+
+	nodes := tree.Root.(*parse.ProgramNode).Nodes
+	if len(nodes) == 0 {
+		return
+	}
+	typ, ok := s.checker.Types[nodes[len(nodes)-1]]
+	if !ok {
+		fmt.Fprintln(s.out, "not an expression")
+		return
+	}
+	fmt.Fprintln(s.out, typ)
+}
+
+func (s *session) printVars() {
+	for _, name := range s.scope.TypeNames() {
+		typ, _ := s.scope.ResolveType(name)
+		fmt.Fprintf(s.out, "type %s %s\n", name, typ)
+	}
+	for _, name := range s.scope.Names() {
+		val, _ := s.scope.ResolveLocal(name)
+		typ := "interface"
+		if checked, ok := s.checker.Lookup(name); ok {
+			typ = checked.String()
+		}
+		if str, ok := val.(string); ok {
+			val = strconv.Quote(str)
+		}
+		fmt.Fprintf(s.out, "%s %s = %v\n", name, typ, val)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"myProgrammingLanguage/check"
+	"myProgrammingLanguage/eval"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// testSuffix is the suffix of the names of test files
+const testSuffix = "_test" + module.Extension
+
+// testResult is the outcome of a test function, err is nil if the test passed
+type testResult struct {
+	name    string
+	elapsed time.Duration
+	err     error
+}
+
+// failed reports whether the test failed an assertion, other errors of tests are errors
+func (r testResult) failed() bool {
+	return errors.Is(r.err, eval.AssertionFailed)
+}
+
+// testFile holds the results of the tests of a file, err is set if the file can't be parsed or checked
+type testFile struct {
+	path    string
+	elapsed time.Duration
+	err     error
+	results []testResult
+}
+
+// testRunner runs the tests of files with the given engine
+type testRunner struct {
+	engine   eval.Engine
+	filter   *regexp.Regexp
+	timeout  time.Duration
+	verbose  bool
+	loader   *module.Loader
+	renderer *parse.Renderer
+}
+
+// testCommand runs the test functions of the *_test.pd files and prints a summary
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: palm test [flags] [files or directories]")
+		fmt.Fprintln(flags.Output(), "Test runs the functions named test* of the *_test.pd files, directories are searched recursively.")
+		flags.PrintDefaults()
+	}
+	engineName := flags.String("engine", "tree", "engine running the tests, tree for the tree walker or vm for the bytecode vm")
+	run := flags.String("run", "", "run only the tests whose names match the regular expression")
+	verbose := flags.Bool("v", false, "print the passed tests too")
+	junit := flags.String("junit", "", "write the results as JUnit XML to the file")
+	timeout := flags.Duration("timeout", 0, "fail tests running longer than the given time like 2s, 0 for no limit")
+	color := flags.String("color", "auto", "color diagnostics, auto, always or never")
+	path := searchPathFlag(flags)
+	flags.Parse(args)
+
+	engine, err := eval.ParseEngine(*engineName)
+	var filter *regexp.Regexp
+	if err == nil {
+		filter, err = regexp.Compile(*run)
+	}
+	var renderer *parse.Renderer
+	if err == nil {
+		renderer, err = newRenderer(sourceFlags{color: color}, os.Stdout)
+	}
+	var files []string
+	if err == nil {
+		paths := flags.Args()
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		files, err = testFiles(paths)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Println("no test files")
+		return 0
+	}
+
+	runner := &testRunner{
+		engine:   engine,
+		filter:   filter,
+		timeout:  *timeout,
+		verbose:  *verbose,
+		loader:   module.NewLoader(searchPath(*path)),
+		renderer: renderer,
+	}
+	results := make([]*testFile, len(files))
+	tests, passed, broken := 0, 0, 0
+	for i, file := range files {
+		results[i] = runner.runFile(file)
+		if results[i].err != nil {
+			broken++
+		}
+		for _, result := range results[i].results {
+			tests++
+			if result.err == nil {
+				passed++
+			}
+		}
+	}
+	fmt.Printf("%d tests, %d passed, %d failed", tests, passed, tests-passed)
+	if broken > 0 {
+		fmt.Printf(", %d files with errors", broken)
+	}
+	fmt.Println()
+
+	if *junit != "" {
+		if err := writeJUnit(*junit, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if passed < tests || broken > 0 {
+		return 1
+	}
+	return 0
+}
+
+// testFiles returns the given files and the test files in the given directories and their subdirectories
+func testFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(file, testSuffix) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// runFile parses and checks the file and runs its tests, it prints the failed tests and a line for the file
+func (r *testRunner) runFile(path string) *testFile {
+	start := time.Now()
+	file := &testFile{path: path}
+	file.err = r.runTests(file)
+	file.elapsed = time.Since(start)
+
+	failed := 0
+	for _, result := range file.results {
+		if result.err != nil {
+			failed++
+		}
+	}
+	switch {
+	case file.err != nil:
+		fmt.Printf("FAIL\t%s\t%s\n", path, file.err)
+	case failed > 0:
+		fmt.Printf("FAIL\t%s\t%d of %d tests failed\t%.3fs\n", path, failed, len(file.results), file.elapsed.Seconds())
+	default:
+		fmt.Printf("ok\t%s\t%d tests\t%.3fs\n", path, len(file.results), file.elapsed.Seconds())
+	}
+	return file
+}
+
+func (r *testRunner) runTests(file *testFile) error {
+	src, err := os.ReadFile(file.path)
+	if err != nil {
+		return err
+	}
+	r.renderer.AddSource(file.path, string(src))
+	parser := parse.NewParser(file.path, string(src))
+	tree, err := parser.Parse()
+	if err != nil {
+		return err
+	}
+	if parser.Errors.HasErrors() {
+		r.renderer.RenderAll(os.Stdout, parser.Errors.GetErrors())
+		return errors.New("syntax errors")
+	}
+
+	checker := check.NewChecker(parse.NewErrorContainer())
+	checker.Loader = r.loader
+	checker.Check(tree)
+	addModuleSources(r.renderer, r.loader)
+	if checker.Errors.HasErrors() {
+		r.renderer.RenderAll(os.Stdout, checker.Errors.GetErrors())
+		return errors.New("type errors")
+	}
+
+	for _, node := range tree.Root.(*parse.ProgramNode).Nodes {
+		fn, ok := node.(*parse.FunctionNode)
+		if !ok || !strings.HasPrefix(fn.Name.Val, "test") || !r.filter.MatchString(fn.Name.Val) {
+			continue
+		}
+		result := r.runTest(file.path, tree, fn)
+		file.results = append(file.results, result)
+		r.report(result)
+	}
+	return nil
+}
+
+// runTest runs the test function in a new global scope. The top level of the file runs before every test,
+// so tests don't share global variables, and the modules are evaluated again for every test.
+func (r *testRunner) runTest(path string, tree *parse.SyntaxTree, fn *parse.FunctionNode) testResult {
+	start := time.Now()
+	result := testResult{name: fn.Name.Val}
+	if len(fn.Params) > 0 {
+		result.err = fmt.Errorf("test function %s must not have parameters", fn.Name.Val)
+		return result
+	}
+
+	ctx := context.Background()
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	scope := eval.NewGlobalScope()
+	imports := eval.NewImporter(r.loader, r.engine, eval.Limits{})
+	_, result.err = imports.Run(ctx, path, tree, scope)
+	if result.err == nil {
+		_, result.err = imports.Run(ctx, path, callTree(path, fn), scope)
+	}
+	result.elapsed = time.Since(start)
+	return result
+}
+
+// callTree returns a program calling the test function, the call is located at the name of the function
+func callTree(path string, fn *parse.FunctionNode) *parse.SyntaxTree {
+	tree := &parse.SyntaxTree{Name: path}
+	callee := parse.NewCallExpressionNode(tree, fn.Name)
+	tree.Root = parse.NewProgramNode(tree, []parse.Node{parse.NewCallNode(tree, callee, fn.Name, nil, fn.Name)})
+	return tree
+}
+
+// report prints the failed tests with their errors and the passed tests if the runner is verbose
+func (r *testRunner) report(result testResult) {
+	if result.err == nil {
+		if r.verbose {
+			fmt.Printf("--- PASS: %s (%.3fs)\n", result.name, result.elapsed.Seconds())
+		}
+		return
+	}
+
+	fmt.Printf("--- FAIL: %s (%.3fs)\n", result.name, result.elapsed.Seconds())
+	var runtimeErr *eval.RuntimeError
+	var syntaxErr *eval.SyntaxError
+	switch {
+	case errors.As(result.err, &runtimeErr):
+		addModuleSources(r.renderer, r.loader)
+		r.renderer.Render(os.Stdout, runtimeErr.Diagnostic())
+		if !result.failed() {
+			fmt.Print(runtimeErr.StackTrace())
+		}
+	case errors.As(result.err, &syntaxErr):
+		addModuleSources(r.renderer, r.loader)
+		r.renderer.RenderAll(os.Stdout, syntaxErr.Errors)
+	default:
+		fmt.Println(result.err)
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the results in the JUnit XML format, every file is a test suite. Failed assertions are
+// failures and other errors are errors, files which can't be parsed or checked have an error test case.
+func writeJUnit(path string, files []*testFile) error {
+	suites := junitTestSuites{}
+	var total time.Duration
+	for _, file := range files {
+		suite := junitTestSuite{Name: file.path, Time: seconds(file.elapsed)}
+		if file.err != nil {
+			suite.Tests, suite.Errors = 1, 1
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      filepath.Base(file.path),
+				Classname: file.path,
+				Time:      seconds(file.elapsed),
+				Error:     &junitProblem{Message: file.err.Error(), Type: "load error", Text: file.err.Error()},
+			})
+		}
+		for _, result := range file.results {
+			testCase := junitTestCase{Name: result.name, Classname: file.path, Time: seconds(result.elapsed)}
+			if result.err != nil {
+				problem := junitProblem{Message: result.err.Error(), Type: "error", Text: result.err.Error()}
+				var runtimeErr *eval.RuntimeError
+				if errors.As(result.err, &runtimeErr) {
+					problem.Message, problem.Type = runtimeErr.Msg, runtimeErr.Kind.String()
+					problem.Text += "\n" + runtimeErr.StackTrace()
+				}
+				if result.failed() {
+					testCase.Failure = &problem
+					suite.Failures++
+				} else {
+					testCase.Error = &problem
+					suite.Errors++
+				}
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		total += file.elapsed
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = seconds(total)
+
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(out, '\n')...), 0o644)
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
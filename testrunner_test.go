@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"myProgrammingLanguage/eval"
+	"myProgrammingLanguage/module"
+	"myProgrammingLanguage/parse"
+)
+
+// writeFiles creates the files in a temporary directory and returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// captureStdout returns what f writes to standard output
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		output <- string(out)
+	}()
+	defer func() {
+		os.Stdout = stdout
+		w.Close()
+	}()
+	f()
+	os.Stdout = stdout
+	w.Close()
+	return <-output
+}
+
+func newTestRunner(engine eval.Engine, run string) *testRunner {
+	return &testRunner{
+		engine:   engine,
+		filter:   regexp.MustCompile(run),
+		loader:   module.NewLoader(nil),
+		renderer: parse.NewRenderer(false),
+	}
+}
+
+func TestTestFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a_test.pd":     "",
+		"a.pd":          "",
+		"sub/b_test.pd": "",
+		"sub/test.pd":   "",
+	})
+	files, err := testFiles([]string{dir, filepath.Join(dir, "a.pd")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a_test.pd"), filepath.Join(dir, "sub", "b_test.pd"), filepath.Join(dir, "a.pd")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got %v, want %v", files, want)
+	}
+}
+
+func TestRunnerIsolatesTests(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"counter.pd": "count := 0\nfn Inc() int {\ncount += 1\nreturn count\n}",
+		"isolation_test.pd": `import "./counter"
+n := 0
+fn testFirst() {
+    n += 1
+    assertEq(n, 1)
+    assertEq(counter.Inc(), 1)
+}
+fn testSecond() {
+    n += 1
+    assertEq(n, 1)
+    assertEq(counter.Inc(), 1)
+}
+fn helper() { assert(false, "not a test") }
+`,
+	})
+	for _, engine := range []eval.Engine{eval.TreeWalker, eval.BytecodeVM} {
+		var file *testFile
+		captureStdout(t, func() {
+			file = newTestRunner(engine, "").runFile(filepath.Join(dir, "isolation_test.pd"))
+		})
+		if file.err != nil || len(file.results) != 2 {
+			t.Fatalf("engine %d: got %v and %d results, want two tests", engine, file.err, len(file.results))
+		}
+		for _, result := range file.results {
+			if result.err != nil {
+				t.Errorf("engine %d: %s: %v", engine, result.name, result.err)
+			}
+		}
+	}
+}
+
+func TestRunnerReports(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"report_test.pd": `fn testPass() { assertEq(1 + 1, 2) }
+fn testFail() { assertEq(1 + 1, 3) }
+fn testError() {
+    a := [1]
+    a[2]
+}
+fn testParams(int n) { }
+`,
+		"broken_test.pd": "fn testBroken() { x := 1 + \"a\" }",
+	})
+	path := filepath.Join(dir, "report_test.pd")
+
+	var file *testFile
+	output := captureStdout(t, func() {
+		file = newTestRunner(eval.TreeWalker, "").runFile(path)
+	})
+	var outcomes []string
+	for _, result := range file.results {
+		outcome := "pass"
+		if result.failed() {
+			outcome = "fail"
+		} else if result.err != nil {
+			outcome = "error"
+		}
+		outcomes = append(outcomes, result.name+" "+outcome)
+	}
+	if got := strings.Join(outcomes, ", "); got != "testPass pass, testFail fail, testError error, testParams error" {
+		t.Errorf("got %s", got)
+	}
+	for _, want := range []string{"--- FAIL: testFail", "--- FAIL: testError", "at testError", "FAIL\t" + path + "\t3 of 4 tests failed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output %q doesn't contain %q", output, want)
+		}
+	}
+	if strings.Contains(output, "testPass") {
+		t.Errorf("output %q reports the passed test without -v", output)
+	}
+
+	output = captureStdout(t, func() {
+		file = newTestRunner(eval.TreeWalker, "Pass").runFile(path)
+	})
+	if len(file.results) != 1 || !strings.HasPrefix(output, "ok\t"+path+"\t1 tests") {
+		t.Errorf("-run Pass: got %d results and output %q", len(file.results), output)
+	}
+
+	var broken *testFile
+	output = captureStdout(t, func() {
+		broken = newTestRunner(eval.TreeWalker, "").runFile(filepath.Join(dir, "broken_test.pd"))
+	})
+	if broken.err == nil || len(broken.results) != 0 || !strings.Contains(output, "mismatched types int and string") {
+		t.Errorf("file with type errors: got %v and output %q", broken.err, output)
+	}
+
+	junit := filepath.Join(dir, "junit.xml")
+	if err := writeJUnit(junit, []*testFile{file, broken}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(junit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 2 || suites.Failures != 0 || suites.Errors != 1 || len(suites.Suites) != 2 || suites.Suites[1].Cases[0].Error == nil {
+		t.Errorf("got junit %+v", suites)
+	}
+}
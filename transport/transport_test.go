@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, map[string]int{"seq": 1}); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("Content-Type: application/json\r\nContent-Length: 2\r\n\r\n{}")
+	r := bufio.NewReader(&buf)
+	for _, want := range []string{`{"seq":1}`, `{}`} {
+		body, err := ReadMessage(r)
+		if err != nil || string(body) != want {
+			t.Errorf("got %q, %v, want %s", body, err, want)
+		}
+	}
+	if _, err := ReadMessage(r); err != io.EOF {
+		t.Errorf("got %v at the end of the input, want EOF", err)
+	}
+}
+
+func TestReadMessageRejectsLengths(t *testing.T) {
+	tests := map[string]string{
+		"Content-Length: -1\r\n\r\n":         `invalid Content-Length header "-1"`,
+		"Content-Length: x\r\n\r\n":          `invalid Content-Length header "x"`,
+		"Content-Type: text\r\n\r\n":         `invalid Content-Length header ""`,
+		"Content-Length: 1000000000\r\n\r\n": "message of 1000000000 bytes exceeds the limit of 67108864 bytes",
+	}
+	for input, want := range tests {
+		_, err := ReadMessage(bufio.NewReader(strings.NewReader(input)))
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: got %v, want %s", input, err, want)
+		}
+	}
+}